@@ -40,7 +40,13 @@ func isDir(path string, d fs.DirEntry) bool {
 //	├── smydir1 -> dir
 //	└── smydir2 -> dir
 func IgnoreDuplicateDirs(walkFn fs.WalkDirFunc) fs.WalkDirFunc {
-	filter := NewEntryFilter()
+	return IgnoreDuplicateDirsFilter(walkFn, NewEntryFilter())
+}
+
+// IgnoreDuplicateDirsFilter is like [IgnoreDuplicateDirs], but uses filter
+// to record visited directories. This allows a memory-bounded filter like
+// [BloomEntryFilter] to be used.
+func IgnoreDuplicateDirsFilter(walkFn fs.WalkDirFunc, filter Entryer) fs.WalkDirFunc {
 	return func(path string, d fs.DirEntry, err error) error {
 		// Call walkFn before checking the entry filter so that we
 		// don't record directories that are skipped with SkipDir.
@@ -73,7 +79,13 @@ func IgnoreDuplicateDirs(walkFn fs.WalkDirFunc) fs.WalkDirFunc {
 // This can significantly slow Walk as os.Stat() is called for each path
 // (on Windows, os.Stat() is only needed for symlinks).
 func IgnoreDuplicateFiles(walkFn fs.WalkDirFunc) fs.WalkDirFunc {
-	filter := NewEntryFilter()
+	return IgnoreDuplicateFilesFilter(walkFn, NewEntryFilter())
+}
+
+// IgnoreDuplicateFilesFilter is like [IgnoreDuplicateFiles], but uses filter
+// to record visited files. This allows a memory-bounded filter like
+// [BloomEntryFilter] to be used.
+func IgnoreDuplicateFilesFilter(walkFn fs.WalkDirFunc, filter Entryer) fs.WalkDirFunc {
 	return func(path string, d fs.DirEntry, err error) error {
 		// Skip all duplicate files, directories, and links
 		if filter.Entry(path, d) {
@@ -100,3 +112,194 @@ func IgnorePermissionErrors(walkFn fs.WalkDirFunc) fs.WalkDirFunc {
 		return walkFn(path, d, err)
 	}
 }
+
+// A Middleware wraps a [fs.WalkDirFunc] to change its behavior. The
+// [IgnoreDuplicateDirs], [IgnoreDuplicateFiles], [IgnorePermissionErrors]
+// and [IgnoreHidden] adapters are all Middleware.
+type Middleware func(walkFn fs.WalkDirFunc) fs.WalkDirFunc
+
+// Chain wraps walkFn with the Middleware mw and returns the result.
+//
+// The first Middleware is the outermost and the last is the innermost, which
+// means that Chain(fn, a, b, c) is equivalent to a(b(c(fn))). For each entry:
+//
+//   - Middleware are called in the order they are provided and each one may
+//     prevent the Middleware after it (and walkFn) from seeing the entry.
+//
+//   - The value returned by walkFn is returned to the Middleware in reverse
+//     order, so the first Middleware sees the final result (SkipDir,
+//     [ErrTraverseLink], [ErrSkipFiles] or an error) and may change it.
+//
+// The order matters when mixing filtering and deduplication. For example,
+// [IgnoreDuplicateDirs] only records a directory after the functions it wraps
+// return and does not record directories that they skip with SkipDir.
+// Therefore, Chain(fn, IgnoreDuplicateDirs, IgnoreHidden) will not record
+// hidden directories as visited, whereas Chain(fn, IgnoreHidden,
+// IgnoreDuplicateDirs) will never call IgnoreDuplicateDirs for them. Likewise,
+// Middleware that drop entries should generally come after (be wrapped by)
+// Middleware that follow symbolic links, such as [IgnoreDuplicateFiles],
+// otherwise the links that they drop will not be followed.
+//
+// Middleware that drop an entry return SkipDir for directories (and
+// symbolic links) that should not be traversed and nil otherwise.
+func Chain(walkFn fs.WalkDirFunc, mw ...Middleware) fs.WalkDirFunc {
+	for i := len(mw) - 1; i >= 0; i-- {
+		walkFn = mw[i](walkFn)
+	}
+	return walkFn
+}
+
+// isRoot returns if d is the root of the walk.
+func isRoot(d fs.DirEntry) bool {
+	return DirEntryDepth(d) == 0
+}
+
+// IgnoreHidden wraps walkFn so that hidden files, those with names that
+// start with a dot ("."), are ignored. Hidden directories and symbolic links
+// are skipped with SkipDir and are not traversed. The root of the walk is
+// never ignored, even if its name starts with a dot.
+//
+// Only the name of the entry is checked so this does not require a stat call.
+// The returned [fs.WalkDirFunc] may be reused. See [IgnoreHiddenWith] for
+// allowing specific hidden names or using extended attributes to hide files.
+func IgnoreHidden(walkFn fs.WalkDirFunc) fs.WalkDirFunc {
+	return func(path string, d fs.DirEntry, err error) error {
+		if d != nil && !isRoot(d) && isHiddenName(d.Name()) {
+			return skipEntry(d)
+		}
+		return walkFn(path, d, err)
+	}
+}
+
+func isHiddenName(name string) bool {
+	return len(name) != 0 && name[0] == '.'
+}
+
+// IgnoreHiddenOptions configures the behavior of [IgnoreHiddenWith].
+type IgnoreHiddenOptions struct {
+	// Allow is a list of hidden names that should not be ignored,
+	// such as ".github" or ".gitignore".
+	Allow []string
+
+	// Xattr is the name of an extended attribute (for example
+	// "user.hidden") that marks a file as hidden. If Xattr is not empty,
+	// files and directories that have the extended attribute are also
+	// ignored. Checking for the attribute requires a system call for
+	// each entry that is not already hidden by its name.
+	//
+	// This option is only supported on Linux and is ignored on
+	// other platforms. Symbolic links are never checked.
+	Xattr string
+
+	// DirsOnly restricts the options to directories and symbolic links
+	// to directories, hidden files are passed to walkFn. Checking if a
+	// hidden symbolic link refers to a directory requires a stat call.
+	DirsOnly bool
+}
+
+// IgnoreHiddenWith returns a Middleware that behaves like [IgnoreHidden]
+// but is configured by opts.
+func IgnoreHiddenWith(opts IgnoreHiddenOptions) Middleware {
+	allow := make(map[string]struct{}, len(opts.Allow))
+	for _, name := range opts.Allow {
+		allow[name] = struct{}{}
+	}
+	xattr := opts.Xattr
+	dirsOnly := opts.DirsOnly
+	return func(walkFn fs.WalkDirFunc) fs.WalkDirFunc {
+		return func(path string, d fs.DirEntry, err error) error {
+			if d == nil || isRoot(d) {
+				return walkFn(path, d, err)
+			}
+			name := d.Name()
+			if isHiddenName(name) {
+				if _, ok := allow[name]; !ok && (!dirsOnly || isDir(path, d)) {
+					return skipEntry(d)
+				}
+			} else if xattr != "" && d.Type()&os.ModeSymlink == 0 &&
+				(!dirsOnly || d.IsDir()) && hasXattr(path, xattr) {
+				return skipEntry(d)
+			}
+			return walkFn(path, d, err)
+		}
+	}
+}
+
+// skipEntry returns the error that should be returned to skip d: SkipDir
+// for directories and symbolic links (which may be traversed) and nil for
+// everything else.
+func skipEntry(d fs.DirEntry) error {
+	if d.IsDir() || d.Type()&os.ModeSymlink != 0 {
+		return filepath.SkipDir
+	}
+	return nil
+}
+
+// MatchName returns a Middleware that only calls walkFn for entries with
+// names for which match returns true. Directories that do not match are
+// still traversed, but walkFn is not called for them (this is similar
+// to the -name flag of find(1)). Entries passed with an error are always
+// passed to walkFn. The root of the walk is always passed to walkFn.
+func MatchName(match func(name string) bool) Middleware {
+	return func(walkFn fs.WalkDirFunc) fs.WalkDirFunc {
+		return func(path string, d fs.DirEntry, err error) error {
+			if err == nil && d != nil && !isRoot(d) && !match(d.Name()) {
+				return nil
+			}
+			return walkFn(path, d, err)
+		}
+	}
+}
+
+// SkipName returns a Middleware that ignores entries with names for which
+// skip returns true. Directories and symbolic links that are skipped are
+// not traversed (this is similar to the -prune flag of find(1)). The root
+// of the walk is never skipped.
+func SkipName(skip func(name string) bool) Middleware {
+	return func(walkFn fs.WalkDirFunc) fs.WalkDirFunc {
+		return func(path string, d fs.DirEntry, err error) error {
+			if d != nil && !isRoot(d) && skip(d.Name()) {
+				return skipEntry(d)
+			}
+			return walkFn(path, d, err)
+		}
+	}
+}
+
+// MatchType returns a Middleware that only calls walkFn for entries with
+// one of the provided types (as reported by [fs.DirEntry.Type]). Regular
+// files have a type of 0. Directories that do not match are still traversed,
+// but walkFn is not called for them. Entries passed with an error are always
+// passed to walkFn.
+//
+// Symbolic links have a type of [fs.ModeSymlink] and are matched as such.
+func MatchType(types ...fs.FileMode) Middleware {
+	return func(walkFn fs.WalkDirFunc) fs.WalkDirFunc {
+		return func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d == nil {
+				return walkFn(path, d, err)
+			}
+			typ := d.Type().Type()
+			for _, t := range types {
+				if typ == t.Type() {
+					return walkFn(path, d, err)
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// IgnoreErrors returns a Middleware that ignores errors for which ignore
+// returns true. The walkFn is not called for ignored errors.
+// IgnoreErrors(os.IsPermission) is equivalent to [IgnorePermissionErrors].
+func IgnoreErrors(ignore func(err error) bool) Middleware {
+	return func(walkFn fs.WalkDirFunc) fs.WalkDirFunc {
+		return func(path string, d fs.DirEntry, err error) error {
+			if err != nil && ignore(err) {
+				return nil
+			}
+			return walkFn(path, d, err)
+		}
+	}
+}
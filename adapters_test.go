@@ -52,10 +52,10 @@ func TestIgnoreDuplicateDirs(t *testing.T) {
 		"/src/skip":          os.ModeDir,
 	}
 
-	runTest := func(t *testing.T, conf *fastwalk.Config) {
+	runTest := func(t *testing.T, conf *fastwalk.Config, filter fastwalk.Entryer) {
 		var mu sync.Mutex
 		got := make(map[string]os.FileMode)
-		walkFn := fastwalk.IgnoreDuplicateDirs(func(path string, de fs.DirEntry, err error) error {
+		walkFn := fastwalk.IgnoreDuplicateDirsFilter(func(path string, de fs.DirEntry, err error) error {
 			requireNoError(t, err)
 			if err != nil {
 				return err
@@ -86,7 +86,7 @@ func TestIgnoreDuplicateDirs(t *testing.T) {
 				return filepath.SkipDir
 			}
 			return nil
-		})
+		}, filter)
 		if err := fastwalk.Walk(conf, tempdir, walkFn); err != nil {
 			t.Error("fastwalk:", err)
 		}
@@ -97,12 +97,16 @@ func TestIgnoreDuplicateDirs(t *testing.T) {
 	}
 
 	t.Run("NoFollow", func(t *testing.T) {
-		runTest(t, &fastwalk.Config{Follow: false})
+		runTest(t, &fastwalk.Config{Follow: false}, fastwalk.NewEntryFilter())
 	})
 
 	// Test that setting Follow to true has no impact on the behavior
 	t.Run("Follow", func(t *testing.T) {
-		runTest(t, &fastwalk.Config{Follow: true})
+		runTest(t, &fastwalk.Config{Follow: true}, fastwalk.NewEntryFilter())
+	})
+
+	t.Run("BloomEntryFilter", func(t *testing.T) {
+		runTest(t, &fastwalk.Config{Follow: false}, fastwalk.NewBloomEntryFilter(1024, 0.001))
 	})
 
 	t.Run("Error", func(t *testing.T) {
@@ -154,44 +158,56 @@ func TestIgnoreDuplicateFiles(t *testing.T) {
 	}
 	sort.Strings(expectedContents)
 
-	var (
-		mu       sync.Mutex
-		seen     []os.FileInfo
-		contents []string
-	)
-	walkFn := fastwalk.IgnoreDuplicateFiles(func(path string, de fs.DirEntry, err error) error {
-		requireNoError(t, err)
-		fi1, err := fastwalk.StatDirEntry(path, de)
-		if err != nil {
-			t.Error(err)
-			return err
-		}
-		mu.Lock()
-		defer mu.Unlock()
-		for _, fi2 := range seen {
-			if os.SameFile(fi1, fi2) {
-				t.Errorf("Visited file twice: %q (%s) and %q (%s)",
-					path, fi1.Mode(), fi2.Name(), fi2.Mode())
-			}
-		}
-		seen = append(seen, fi1)
-		if fi1.Mode().IsRegular() {
-			data, err := os.ReadFile(path)
+	runTest := func(t *testing.T, adapter func(fs.WalkDirFunc) fs.WalkDirFunc) {
+		var (
+			mu       sync.Mutex
+			seen     []os.FileInfo
+			contents []string
+		)
+		walkFn := adapter(func(path string, de fs.DirEntry, err error) error {
+			requireNoError(t, err)
+			fi1, err := fastwalk.StatDirEntry(path, de)
 			if err != nil {
+				t.Error(err)
 				return err
 			}
-			contents = append(contents, string(data))
+			mu.Lock()
+			defer mu.Unlock()
+			for _, fi2 := range seen {
+				if os.SameFile(fi1, fi2) {
+					t.Errorf("Visited file twice: %q (%s) and %q (%s)",
+						path, fi1.Mode(), fi2.Name(), fi2.Mode())
+				}
+			}
+			seen = append(seen, fi1)
+			if fi1.Mode().IsRegular() {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				contents = append(contents, string(data))
+			}
+			return nil
+		})
+		if err := fastwalk.Walk(nil, tempdir, walkFn); err != nil {
+			t.Fatal(err)
 		}
-		return nil
-	})
-	if err := fastwalk.Walk(nil, tempdir, walkFn); err != nil {
-		t.Fatal(err)
-	}
 
-	sort.Strings(contents)
-	if !reflect.DeepEqual(expectedContents, contents) {
-		t.Errorf("File contents want: %q got: %q", expectedContents, contents)
+		sort.Strings(contents)
+		if !reflect.DeepEqual(expectedContents, contents) {
+			t.Errorf("File contents want: %q got: %q", expectedContents, contents)
+		}
 	}
+
+	t.Run("EntryFilter", func(t *testing.T) {
+		runTest(t, fastwalk.IgnoreDuplicateFiles)
+	})
+
+	t.Run("BloomEntryFilter", func(t *testing.T) {
+		runTest(t, func(fn fs.WalkDirFunc) fs.WalkDirFunc {
+			return fastwalk.IgnoreDuplicateFilesFilter(fn, fastwalk.NewBloomEntryFilter(1024, 0.001))
+		})
+	})
 }
 
 func TestIgnorePermissionErrors(t *testing.T) {
@@ -234,3 +250,221 @@ func TestIgnorePermissionErrors(t *testing.T) {
 		}
 	})
 }
+
+// testMiddleware walks files and returns the relative paths of the entries
+// passed to the innermost WalkDirFunc of the Chain.
+func testMiddleware(t *testing.T, conf *fastwalk.Config, files map[string]string,
+	mw ...fastwalk.Middleware) map[string]os.FileMode {
+
+	tempdir := t.TempDir()
+	testCreateFiles(t, tempdir, files)
+	root := filepath.Join(tempdir, "src")
+
+	var mu sync.Mutex
+	got := make(map[string]os.FileMode)
+	walkFn := fastwalk.Chain(func(path string, de fs.DirEntry, err error) error {
+		requireNoError(t, err)
+		key := filepath.ToSlash(strings.TrimPrefix(path, root))
+		mu.Lock()
+		got[key] = de.Type()
+		mu.Unlock()
+		return nil
+	}, mw...)
+	if err := fastwalk.Walk(conf, root, walkFn); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+	mw := func(name string) fastwalk.Middleware {
+		return func(walkFn fs.WalkDirFunc) fs.WalkDirFunc {
+			return func(path string, d fs.DirEntry, err error) error {
+				calls = append(calls, name+":enter")
+				err = walkFn(path, d, err)
+				calls = append(calls, name+":exit")
+				return err
+			}
+		}
+	}
+	fn := fastwalk.Chain(func(path string, d fs.DirEntry, err error) error {
+		calls = append(calls, "fn")
+		return nil
+	}, mw("a"), mw("b"), mw("c"))
+	if err := fn("", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a:enter", "b:enter", "c:enter", "fn", "c:exit", "b:exit", "a:exit"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("call order:\n got: %q\nwant: %q", calls, want)
+	}
+
+	// The existing adapters are Middleware
+	_ = fastwalk.Chain(noopWalkFunc,
+		fastwalk.IgnorePermissionErrors,
+		fastwalk.IgnoreDuplicateDirs,
+		fastwalk.IgnoreDuplicateFiles,
+	)
+}
+
+func TestIgnoreHidden(t *testing.T) {
+	files := map[string]string{
+		".hidden/a.go": "a",
+		".file.go":     "b",
+		".symdir":      "LINK:foo",
+		"foo/foo.go":   "c",
+		"foo/.foo.go":  "d",
+	}
+	for _, follow := range []bool{false, true} {
+		got := testMiddleware(t, &fastwalk.Config{Follow: follow}, files, fastwalk.IgnoreHidden)
+		want := map[string]os.FileMode{
+			"":            os.ModeDir,
+			"/foo":        os.ModeDir,
+			"/foo/foo.go": 0,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Follow=%t: walk mismatch.\n got:\n%v\nwant:\n%v",
+				follow, formatFileModes(got), formatFileModes(want))
+		}
+	}
+
+	// The root is never ignored
+	tempdir := t.TempDir()
+	root := filepath.Join(tempdir, ".root")
+	if err := writeFile(filepath.Join(root, "file.go"), "a", 0644); err != nil {
+		t.Fatal(err)
+	}
+	var count atomic.Int32
+	err := fastwalk.Walk(nil, root, fastwalk.IgnoreHidden(func(_ string, _ fs.DirEntry, err error) error {
+		count.Add(1)
+		return err
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := count.Load(); n != 2 {
+		t.Errorf("visited %d entries want: %d", n, 2)
+	}
+}
+
+func TestMatchName(t *testing.T) {
+	got := testMiddleware(t, nil, map[string]string{
+		"foo/foo.go":  "a",
+		"foo/foo.txt": "b",
+		"bar.go":      "c",
+	}, fastwalk.MatchName(func(name string) bool {
+		return filepath.Ext(name) == ".go"
+	}))
+	want := map[string]os.FileMode{
+		"":            os.ModeDir,
+		"/bar.go":     0,
+		"/foo/foo.go": 0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk mismatch.\n got:\n%v\nwant:\n%v", formatFileModes(got), formatFileModes(want))
+	}
+}
+
+func TestSkipName(t *testing.T) {
+	got := testMiddleware(t, nil, map[string]string{
+		"node_modules/a.js": "a",
+		"foo/node_modules":  "LINK:../node_modules",
+		"foo/foo.js":        "b",
+	}, fastwalk.SkipName(func(name string) bool {
+		return name == "node_modules"
+	}))
+	want := map[string]os.FileMode{
+		"":            os.ModeDir,
+		"/foo":        os.ModeDir,
+		"/foo/foo.js": 0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk mismatch.\n got:\n%v\nwant:\n%v", formatFileModes(got), formatFileModes(want))
+	}
+}
+
+func TestMatchType(t *testing.T) {
+	got := testMiddleware(t, nil, map[string]string{
+		"foo/foo.go": "a",
+		"foo/link":   "LINK:foo.go",
+		"bar.go":     "b",
+	}, fastwalk.MatchType(0, fs.ModeSymlink))
+	want := map[string]os.FileMode{
+		"/bar.go":     0,
+		"/foo/foo.go": 0,
+		"/foo/link":   fs.ModeSymlink,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk mismatch.\n got:\n%v\nwant:\n%v", formatFileModes(got), formatFileModes(want))
+	}
+}
+
+func TestIgnoreErrors(t *testing.T) {
+	errIgnored := errors.New("ignored")
+	errReturned := errors.New("returned")
+	var called bool
+	fn := fastwalk.IgnoreErrors(func(err error) bool {
+		return errors.Is(err, errIgnored)
+	})(func(path string, _ fs.DirEntry, err error) error {
+		called = true
+		return err
+	})
+	if err := fn("", nil, errIgnored); err != nil || called {
+		t.Errorf("IgnoreErrors: got: %v, called: %t; want: <nil>, false", err, called)
+	}
+	if err := fn("", nil, errReturned); err != errReturned || !called {
+		t.Errorf("IgnoreErrors: got: %v, called: %t; want: %v, true", err, called, errReturned)
+	}
+}
+
+func TestIgnoreHiddenWith(t *testing.T) {
+	got := testMiddleware(t, nil, map[string]string{
+		".github/ci.yml": "a",
+		".gitignore":     "b",
+		".hidden/a.go":   "c",
+		"foo/.foo.go":    "d",
+		"foo/foo.go":     "e",
+	}, fastwalk.IgnoreHiddenWith(fastwalk.IgnoreHiddenOptions{
+		Allow: []string{".github", ".gitignore"},
+	}))
+	want := map[string]os.FileMode{
+		"":                os.ModeDir,
+		"/.github":        os.ModeDir,
+		"/.github/ci.yml": 0,
+		"/.gitignore":     0,
+		"/foo":            os.ModeDir,
+		"/foo/foo.go":     0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk mismatch.\n got:\n%v\nwant:\n%v", formatFileModes(got), formatFileModes(want))
+	}
+}
+
+func TestIgnoreHiddenWithDirsOnly(t *testing.T) {
+	got := testMiddleware(t, nil, map[string]string{
+		".github/ci.yml": "a",
+		".gitignore":     "b",
+		".hidden/a.go":   "c",
+		"foo/.foo.go":    "d",
+		"foo/.link":      "LINK:../.hidden",
+		"foo/.filelink":  "LINK:foo.go",
+		"foo/foo.go":     "e",
+	}, fastwalk.IgnoreHiddenWith(fastwalk.IgnoreHiddenOptions{
+		Allow:    []string{".github"},
+		DirsOnly: true,
+	}))
+	want := map[string]os.FileMode{
+		"":                os.ModeDir,
+		"/.github":        os.ModeDir,
+		"/.github/ci.yml": 0,
+		"/.gitignore":     0,
+		"/foo":            os.ModeDir,
+		"/foo/.foo.go":    0,
+		"/foo/.filelink":  os.ModeSymlink,
+		"/foo/foo.go":     0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk mismatch.\n got:\n%v\nwant:\n%v", formatFileModes(got), formatFileModes(want))
+	}
+}
@@ -0,0 +1,197 @@
+package fastwalk
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// checkpointInterval is how often WalkResume saves its progress.
+var checkpointInterval = time.Minute
+
+// ErrCheckpointCorrupt is returned by [WalkResume] if the checkpoint file
+// is not a valid checkpoint. The checkpoint can be removed to restart the
+// walk.
+var ErrCheckpointCorrupt = errors.New("fastwalk: corrupt checkpoint file")
+
+// ErrCheckpointRoot is returned by [WalkResume] if the checkpoint file was
+// saved by a walk of a different root.
+var ErrCheckpointRoot = errors.New("fastwalk: checkpoint is for a different root")
+
+// ErrCheckpointOrdered is returned by [WalkResume] if [Config.Ordered] is
+// true.
+var ErrCheckpointOrdered = errors.New("fastwalk: WalkResume does not support Config.Ordered")
+
+// A checkpoint file contains the directories that are waiting to be walked,
+// which are stored as spill records (see spillQueue):
+//
+//	magic     [len(checkpointMagic)]byte
+//	followed  uint64 (number of symlinks traversed)
+//	len(root) uint64
+//	root      []byte
+//	records   []byte
+const checkpointMagic = "fastwalk checkpoint 1\n"
+
+// WalkResume is like [Walk] but periodically saves the progress of the walk
+// to the file checkpoint, which allows a walk that was interrupted (for
+// example by a restart or crash) to be resumed by calling WalkResume again
+// with the same arguments. The directories that were walked before the last
+// checkpoint was saved are not read again.
+//
+// The checkpoint contains the directories that are waiting to be walked.
+// To save it the walk is paused until the directories being read have been
+// walked, so the walkFn may be called again for entries of directories that
+// were walked after the last checkpoint was saved. The checkpoint file is
+// removed once the walk completes successfully and is not updated once the
+// walk has stopped with an error.
+//
+// The Config should be the same as the one used to start the walk.
+// [Config.Ordered] is not supported. If the checkpoint cannot be used
+// WalkResume returns [ErrCheckpointCorrupt] or [ErrCheckpointRoot].
+func WalkResume(conf *Config, root, checkpoint string, walkFn fs.WalkDirFunc) error {
+	return walkTree(conf, root, walkFn, &walkOptions{checkpoint: checkpoint})
+}
+
+// resume pushes the directories saved in the checkpoint, if it exists, or
+// rootItem to the scheduler.
+func (w *walker) resume(path string, rootItem walkItem) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			w.sched.push(0, rootItem)
+			return nil
+		}
+		return err
+	}
+	if len(data) < len(checkpointMagic)+16 || string(data[:len(checkpointMagic)]) != checkpointMagic {
+		return ErrCheckpointCorrupt
+	}
+	data = data[len(checkpointMagic):]
+	followed := readUint64(data)
+	n := readUint64(data[8:])
+	data = data[16:]
+	if uint64(len(data)) < n {
+		return ErrCheckpointCorrupt
+	}
+	if string(data[:n]) != w.root {
+		return ErrCheckpointRoot
+	}
+	data = data[n:]
+
+	var items []walkItem
+	for len(data) > 0 {
+		it, n := parseSpillRecord(data, w.statLimit)
+		if n == 0 {
+			return ErrCheckpointCorrupt
+		}
+		items = append(items, it)
+		data = data[n:]
+	}
+	w.followed.Store(int64(followed))
+	for _, it := range items {
+		w.sched.push(0, it)
+	}
+	if len(items) == 0 {
+		w.sched.stop(nil) // nothing left to walk
+	}
+	return nil
+}
+
+// A checkpointer periodically saves the progress of a walk.
+type checkpointer struct {
+	w    *walker
+	path string
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func (w *walker) startCheckpoints(path string) *checkpointer {
+	c := &checkpointer{w: w, path: path, done: make(chan struct{})}
+	c.wg.Add(1)
+	go c.run()
+	return c
+}
+
+func (c *checkpointer) run() {
+	defer c.wg.Done()
+	tick := time.NewTicker(checkpointInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-tick.C:
+			if err := c.w.writeCheckpoint(c.path); err != nil {
+				c.w.sched.stop(err)
+				return
+			}
+		}
+	}
+}
+
+// stop stops the checkpointer and waits for it to exit.
+func (c *checkpointer) stop() {
+	close(c.done)
+	c.wg.Wait()
+}
+
+// writeCheckpoint saves the directories waiting to be walked to path. The
+// walk is paused while the checkpoint is written.
+func (w *walker) writeCheckpoint(path string) error {
+	s := w.sched
+	s.ckpt.Lock()
+	defer s.ckpt.Unlock()
+	if s.stopped.Load() {
+		// The walk failed or is complete.
+		return nil
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	b := make([]byte, 0, spillBufferSize)
+	b = append(b, checkpointMagic...)
+	b = appendUint64(b, uint64(w.followed.Load()))
+	b = appendUint64(b, uint64(len(w.root)))
+	b = append(b, w.root...)
+	for _, q := range s.queues {
+		q.mu.Lock()
+		for _, it := range q.items {
+			b = appendSpillRecord(b, it)
+		}
+		q.mu.Unlock()
+		if len(b) >= spillBufferSize {
+			if _, err = f.Write(b); err != nil {
+				return err
+			}
+			b = b[:0]
+		}
+	}
+	if _, err = f.Write(b); err != nil {
+		return err
+	}
+	if s.spill != nil {
+		if _, err = s.spill.WriteTo(f); err != nil {
+			return err
+		}
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	err = os.Rename(f.Name(), path)
+	return err
+}
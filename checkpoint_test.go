@@ -0,0 +1,133 @@
+package fastwalk
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func createCheckpointTestTree(t *testing.T) (root string, paths map[string]bool) {
+	root = filepath.Join(t.TempDir(), "root")
+	paths = map[string]bool{root: true}
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 10; j++ {
+			dir := filepath.Join(root, "d"+strconv.Itoa(i), "s"+strconv.Itoa(j))
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			file := filepath.Join(dir, "file.txt")
+			if err := os.WriteFile(file, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			paths[filepath.Dir(dir)] = true
+			paths[dir] = true
+			paths[file] = true
+		}
+	}
+	return root, paths
+}
+
+func TestWalkResume(t *testing.T) {
+	defer func(d time.Duration) { checkpointInterval = d }(checkpointInterval)
+	checkpointInterval = 5 * time.Millisecond
+
+	for _, conf := range []Config{
+		{NumWorkers: 4},
+		{NumWorkers: 4, MaxQueuedDirs: 4},
+	} {
+		t.Run("MaxQueuedDirs="+strconv.Itoa(conf.MaxQueuedDirs), func(t *testing.T) {
+			root, want := createCheckpointTestTree(t)
+			checkpoint := filepath.Join(t.TempDir(), "checkpoint")
+
+			// Stop the first walk with an error part way through.
+			errStop := errors.New("stop")
+			var mu sync.Mutex
+			first := make(map[string]int)
+			err := WalkResume(&conf, root, checkpoint, func(path string, _ fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				first[path]++
+				n := len(first)
+				mu.Unlock()
+				if n >= len(want)/2 {
+					return errStop
+				}
+				// Slow the walk down so that checkpoints are saved.
+				time.Sleep(checkpointInterval / 10)
+				return nil
+			})
+			if err != errStop {
+				t.Fatalf("err = %v; want: %v", err, errStop)
+			}
+			if _, err := os.Stat(checkpoint); err != nil {
+				t.Fatal(err)
+			}
+
+			second := make(map[string]int)
+			err = WalkResume(&conf, root, checkpoint, func(path string, _ fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				second[path]++
+				mu.Unlock()
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+				t.Errorf("checkpoint was not removed: %v", err)
+			}
+			if second[root] != 0 {
+				t.Error("resumed walk visited the root")
+			}
+			for path := range want {
+				if first[path]+second[path] == 0 {
+					t.Errorf("path not visited: %s", path)
+				}
+				if second[path] > 1 {
+					t.Errorf("path visited %d times: %s", second[path], path)
+				}
+			}
+			if len(second) >= len(want) {
+				t.Errorf("resumed walk visited %d paths; want: < %d", len(second), len(want))
+			}
+		})
+	}
+}
+
+func TestWalkResumeErrors(t *testing.T) {
+	root, _ := createCheckpointTestTree(t)
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint")
+	fn := func(path string, _ fs.DirEntry, err error) error { return err }
+
+	if err := WalkResume(&Config{Ordered: true}, root, checkpoint, fn); err != ErrCheckpointOrdered {
+		t.Errorf("Ordered: err = %v; want: %v", err, ErrCheckpointOrdered)
+	}
+
+	if err := os.WriteFile(checkpoint, []byte("not a checkpoint"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WalkResume(nil, root, checkpoint, fn); err != ErrCheckpointCorrupt {
+		t.Errorf("Corrupt: err = %v; want: %v", err, ErrCheckpointCorrupt)
+	}
+
+	other := []byte(checkpointMagic)
+	other = appendUint64(other, 0)
+	other = appendUint64(other, uint64(len("/other")))
+	other = append(other, "/other"...)
+	if err := os.WriteFile(checkpoint, other, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WalkResume(nil, root, checkpoint, fn); err != ErrCheckpointRoot {
+		t.Errorf("Root: err = %v; want: %v", err, ErrCheckpointRoot)
+	}
+}
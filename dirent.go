@@ -1,8 +1,10 @@
 package fastwalk
 
 import (
+	"errors"
 	"io/fs"
 	"os"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"syscall"
@@ -31,6 +33,61 @@ func loadFileInfo(pinfo **fileInfo) *fileInfo {
 	return fi
 }
 
+type linkInfo struct {
+	once   sync.Once
+	target string
+	err    error
+}
+
+func loadLinkInfo(plink **linkInfo) *linkInfo {
+	ptr := (*unsafe.Pointer)(unsafe.Pointer(plink))
+	li := (*linkInfo)(atomic.LoadPointer(ptr))
+	if li == nil {
+		li = &linkInfo{}
+		if !atomic.CompareAndSwapPointer(ptr, nil, unsafe.Pointer(li)) {
+			li = (*linkInfo)(atomic.LoadPointer(ptr))
+		}
+	}
+	return li
+}
+
+// maxLinkHops is the maximum number of symbolic links followed by
+// resolveLink, which is the same limit used by [filepath.EvalSymlinks].
+const maxLinkHops = 255
+
+// errTooManyLinks is returned by resolveLink if more than maxLinkHops links
+// are followed. syscall.ELOOP is not used since it is not defined on all
+// platforms.
+var errTooManyLinks = errors.New("too many levels of symbolic links")
+
+// resolveLink returns the path of the file that the symbolic link at path,
+// which has contents target, ultimately refers to. It is safe to pass a nil
+// limit.
+func resolveLink(path, target string, limit *rateLimiter) (string, error) {
+	link := path
+	for i := 0; ; i++ {
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		limit.wait()
+		fi, err := os.Lstat(target)
+		if err != nil {
+			return "", err
+		}
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return target, nil
+		}
+		if i == maxLinkHops {
+			return "", &os.PathError{Op: "stat", Path: link, Err: errTooManyLinks}
+		}
+		path = target
+		limit.wait()
+		if target, err = os.Readlink(path); err != nil {
+			return "", err
+		}
+	}
+}
+
 // StatDirEntry returns a [fs.FileInfo] describing the named file ([os.Stat]).
 // If de is a [fastwalk.DirEntry] its Stat method is used and the returned
 // FileInfo may be cached from a prior call to Stat. If a cached result is not
@@ -71,3 +128,57 @@ func DirEntryDepth(de fs.DirEntry) int {
 	}
 	return -1
 }
+
+// Readlink returns the destination of the symbolic link described by de
+// ([os.Readlink]). If de is a [SymlinkDirEntry] its Readlink method is used
+// and the result may be cached from a prior call to Readlink.
+//
+// The path argument is only used if de is not a [SymlinkDirEntry].
+// Therefore, de should be the DirEntry describing path.
+func Readlink(path string, de fs.DirEntry) (string, error) {
+	if d, ok := de.(SymlinkDirEntry); ok {
+		return d.Readlink()
+	}
+	return os.Readlink(path)
+}
+
+// Target returns a [DirEntry] describing the file that the symbolic link
+// described by de ultimately refers to (see [SymlinkDirEntry]). If de is a
+// SymlinkDirEntry its Target method is used.
+//
+// The path argument is only used if de is not a [SymlinkDirEntry].
+// Therefore, de should be the DirEntry describing path.
+func Target(path string, de fs.DirEntry) (DirEntry, error) {
+	if de == nil {
+		return nil, &os.PathError{Op: "stat", Path: path, Err: syscall.EINVAL}
+	}
+	if d, ok := de.(SymlinkDirEntry); ok {
+		return d.Target()
+	}
+	depth := 0
+	if d, ok := de.(DirEntry); ok {
+		if de.Type()&os.ModeSymlink == 0 {
+			return d, nil
+		}
+		depth = d.Depth()
+	}
+	if de.Type()&os.ModeSymlink == 0 {
+		fi, err := de.Info()
+		if err != nil {
+			return nil, err
+		}
+		return newTargetDirEntry(path, fi, depth, nil), nil
+	}
+	target, err := os.Readlink(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if path, err = resolveLink(path, target, nil); err != nil {
+		return nil, err
+	}
+	return newTargetDirEntry(path, fi, depth, nil), nil
+}
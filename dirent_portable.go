@@ -8,18 +8,24 @@ package fastwalk
 import (
 	"io/fs"
 	"os"
+	"path/filepath"
+	"runtime"
 	"sort"
 	"sync"
+	"syscall"
 
 	"github.com/charlievieth/fastwalk/internal/fmtdirent"
 )
 
-var _ DirEntry = (*portableDirent)(nil)
+var _ SymlinkDirEntry = (*portableDirent)(nil)
 
 type portableDirent struct {
 	fs.DirEntry
 	parent string
+	info   *fileInfo
 	stat   *fileInfo
+	link   *linkInfo
+	limit  *rateLimiter // Config.MaxStatsPerSecond
 	depth  uint32
 }
 
@@ -31,17 +37,101 @@ func (d *portableDirent) Depth() int {
 	return int(d.depth)
 }
 
+func (d *portableDirent) Info() (fs.FileInfo, error) {
+	info := loadFileInfo(&d.info)
+	info.once.Do(func() {
+		if !infoIsFree(d.DirEntry) {
+			d.limit.wait()
+		}
+		info.FileInfo, info.err = d.DirEntry.Info()
+	})
+	return info.FileInfo, info.err
+}
+
+// infoIsFree reports if the Info method of de returns information loaded
+// when the directory was read, without making a system call. Such calls do
+// not count towards Config.MaxStatsPerSecond.
+func infoIsFree(de fs.DirEntry) bool {
+	switch de.(type) {
+	case *targetDirEntry:
+		return true
+	case *typeDirEntry:
+		return false
+	}
+	// The Info method of the entries returned by os.File.ReadDir only
+	// calls lstat on Unix-like systems.
+	return runtime.GOOS == "windows" || runtime.GOOS == "plan9"
+}
+
 func (d *portableDirent) Stat() (fs.FileInfo, error) {
 	if d.DirEntry.Type()&os.ModeSymlink == 0 {
-		return d.DirEntry.Info()
+		return d.Info()
 	}
 	stat := loadFileInfo(&d.stat)
 	stat.once.Do(func() {
+		d.limit.wait()
 		stat.FileInfo, stat.err = os.Stat(d.parent + string(os.PathSeparator) + d.Name())
 	})
 	return stat.FileInfo, stat.err
 }
 
+func (d *portableDirent) Readlink() (string, error) {
+	if d.DirEntry.Type()&os.ModeSymlink == 0 {
+		return "", &os.PathError{
+			Op:   "readlink",
+			Path: d.parent + string(os.PathSeparator) + d.Name(),
+			Err:  syscall.EINVAL,
+		}
+	}
+	link := loadLinkInfo(&d.link)
+	link.once.Do(func() {
+		d.limit.wait()
+		link.target, link.err = os.Readlink(d.parent + string(os.PathSeparator) + d.Name())
+	})
+	return link.target, link.err
+}
+
+func (d *portableDirent) Target() (DirEntry, error) {
+	if d.DirEntry.Type()&os.ModeSymlink == 0 {
+		return d, nil
+	}
+	target, err := d.Readlink()
+	if err != nil {
+		return nil, err
+	}
+	fi, err := d.Stat()
+	if err != nil {
+		return nil, err
+	}
+	path, err := resolveLink(d.parent+string(os.PathSeparator)+d.Name(), target, d.limit)
+	if err != nil {
+		return nil, err
+	}
+	return newTargetDirEntry(path, fi, int(d.depth), d.limit), nil
+}
+
+// newTargetDirEntry returns a DirEntry for the file at path, which is the
+// target of a symbolic link, with FileInfo fi.
+func newTargetDirEntry(path string, fi fs.FileInfo, depth int, limit *rateLimiter) DirEntry {
+	de := newDirEntry(filepath.Dir(path), &targetDirEntry{
+		name: filepath.Base(path),
+		info: fi,
+	}, depth)
+	de.(*portableDirent).limit = limit
+	return de
+}
+
+// targetDirEntry is a fs.DirEntry for the target of a symbolic link.
+type targetDirEntry struct {
+	name string
+	info fs.FileInfo
+}
+
+func (t *targetDirEntry) Name() string               { return t.name }
+func (t *targetDirEntry) IsDir() bool                { return t.info.IsDir() }
+func (t *targetDirEntry) Type() fs.FileMode          { return t.info.Mode().Type() }
+func (t *targetDirEntry) Info() (fs.FileInfo, error) { return t.info, nil }
+
 func newDirEntry(dirName string, info fs.DirEntry, depth int) DirEntry {
 	return &portableDirent{
 		DirEntry: info,
@@ -54,6 +144,30 @@ func fileInfoToDirEntry(dirname string, fi fs.FileInfo) DirEntry {
 	return newDirEntry(dirname, fs.FileInfoToDirEntry(fi), 0)
 }
 
+// newDirEntryType returns a DirEntry for the file name in directory parent
+// with type typ. The stat calls it makes are limited by limit.
+func newDirEntryType(parent, name string, typ fs.FileMode, depth int, limit *rateLimiter) DirEntry {
+	de := newDirEntry(parent, &typeDirEntry{
+		path: parent + string(os.PathSeparator) + name,
+		name: name,
+		typ:  typ,
+	}, depth)
+	de.(*portableDirent).limit = limit
+	return de
+}
+
+// typeDirEntry is a fs.DirEntry with a known type and lazily loaded info.
+type typeDirEntry struct {
+	path string
+	name string
+	typ  fs.FileMode
+}
+
+func (t *typeDirEntry) Name() string               { return t.name }
+func (t *typeDirEntry) IsDir() bool                { return t.typ.IsDir() }
+func (t *typeDirEntry) Type() fs.FileMode          { return t.typ }
+func (t *typeDirEntry) Info() (fs.FileInfo, error) { return os.Lstat(t.path) }
+
 var direntSlicePool = sync.Pool{
 	New: func() any {
 		a := make([]DirEntry, 0, 32)
@@ -73,6 +187,17 @@ func putDirentSlice(p *[]DirEntry) {
 	}
 }
 
+// visitSorted sorts dents and visits them with v.
+func (v *direntVisitor) visitSorted(mode SortMode, dents []DirEntry) error {
+	sortDirents(mode, dents)
+	for _, d := range dents {
+		if err := v.visit(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func sortDirents(mode SortMode, dents []DirEntry) {
 	if len(dents) <= 1 {
 		return
@@ -5,6 +5,8 @@ package fastwalk
 import (
 	"io/fs"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -132,3 +134,43 @@ func TestSortDirents(t *testing.T) {
 		test(t, dents, SortDirsFirst)
 	})
 }
+
+func TestPortableDirentInfoLimit(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tokens := func(l *rateLimiter) float64 {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.tokens
+	}
+
+	// Info makes a system call: it must wait once and cache the result.
+	l := newRateLimiter(1)
+	de := newDirEntryType(dir, "file", 0, 1, l)
+	fi1, err := de.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi2, err := de.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi1 != fi2 {
+		t.Error("Info() result was not cached")
+	}
+	if n := tokens(l); n >= 0.5 || n < -0.5 {
+		t.Errorf("Info() used %.2f tokens; want: 1", 1-n)
+	}
+
+	// Info is free: it must not wait.
+	l = newRateLimiter(1)
+	de = newTargetDirEntry(filepath.Join(dir, "file"), fi1, 1, l)
+	if _, err := de.Info(); err != nil {
+		t.Fatal(err)
+	}
+	if n := tokens(l); n < 0.5 {
+		t.Errorf("Info() used %.2f tokens; want: 0", 1-n)
+	}
+}
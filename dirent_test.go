@@ -92,6 +92,142 @@ func TestDirent(t *testing.T) {
 		}
 	})
 
+	t.Run("Readlink", func(t *testing.T) {
+		linkEnt, fileEnt := getDirEnts(t)
+		got, err := linkEnt.(fastwalk.SymlinkDirEntry).Readlink()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := filepath.Base(fileName); got != want {
+			t.Errorf("Readlink() = %q; want: %q", got, want)
+		}
+		if _, err := fileEnt.(fastwalk.SymlinkDirEntry).Readlink(); err == nil {
+			t.Error("expected an error calling Readlink on a regular file")
+		}
+
+		// Not a fastwalk.DirEntry
+		fi, err := os.Lstat(linkName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err = fastwalk.Readlink(linkName, fs.FileInfoToDirEntry(fi))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := filepath.Base(fileName); got != want {
+			t.Errorf("Readlink() = %q; want: %q", got, want)
+		}
+	})
+
+	t.Run("Target", func(t *testing.T) {
+		linkEnt, fileEnt := getDirEnts(t)
+		want, err := os.Stat(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		target, err := fastwalk.Target(linkName, linkEnt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target.Name() != filepath.Base(fileName) {
+			t.Errorf("Name() = %q; want: %q", target.Name(), filepath.Base(fileName))
+		}
+		if target.Type() != want.Mode().Type() {
+			t.Errorf("Type() = %s; want: %s", target.Type(), want.Mode().Type())
+		}
+		got, err := target.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !os.SameFile(want, got) {
+			t.Errorf("target mismatch\n got:\n%s\nwant:\n%s",
+				fastwalk.FormatFileInfo(got), fastwalk.FormatFileInfo(want))
+		}
+		if target.Depth() != linkEnt.(fastwalk.DirEntry).Depth() {
+			t.Errorf("Depth() = %d; want: %d", target.Depth(), linkEnt.(fastwalk.DirEntry).Depth())
+		}
+
+		// Target of a non-symlink is the entry itself
+		de := fileEnt.(fastwalk.SymlinkDirEntry)
+		if target, err := de.Target(); err != nil || target != de {
+			t.Errorf("Target() = %v, %v; want: %v, <nil>", target, err, de)
+		}
+	})
+
+	t.Run("TargetChain", func(t *testing.T) {
+		dir := t.TempDir()
+		chainName := filepath.Join(dir, "chain.link")
+		if err := symlink(t, linkName, chainName); err != nil {
+			t.Fatal(err)
+		}
+		want, err := os.Stat(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ents, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var chainEnt fs.DirEntry
+		err = fastwalk.Walk(nil, dir, func(path string, d fs.DirEntry, err error) error {
+			if path == chainName {
+				chainEnt = d
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Test both a fastwalk.DirEntry and the fallback for other
+		// fs.DirEntry implementations.
+		for _, de := range []fs.DirEntry{chainEnt, ents[0]} {
+			target, err := fastwalk.Target(chainName, de)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The Name must be of the file the chain resolves to, not
+			// the first link in it.
+			if target.Name() != filepath.Base(fileName) {
+				t.Errorf("%T: Name() = %q; want: %q", de, target.Name(), filepath.Base(fileName))
+			}
+			got, err := target.Info()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !os.SameFile(want, got) {
+				t.Errorf("%T: target mismatch\n got:\n%s\nwant:\n%s", de,
+					fastwalk.FormatFileInfo(got), fastwalk.FormatFileInfo(want))
+			}
+		}
+	})
+
+	t.Run("Dangling", func(t *testing.T) {
+		dir := t.TempDir()
+		brokenName := filepath.Join(dir, "broken.link")
+		if err := symlink(t, "nonexistent", brokenName); err != nil {
+			t.Fatal(err)
+		}
+		var brokenEnt fastwalk.DirEntry
+		err := fastwalk.Walk(nil, dir, func(path string, d fs.DirEntry, err error) error {
+			if path == brokenName {
+				brokenEnt = d.(fastwalk.DirEntry)
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if brokenEnt == nil {
+			t.Fatal("error walking directory")
+		}
+		if link, err := fastwalk.Readlink(brokenName, brokenEnt); err != nil || link != "nonexistent" {
+			t.Errorf("Readlink() = %q, %v; want: %q, <nil>", link, err, "nonexistent")
+		}
+		if _, err := fastwalk.Target(brokenName, brokenEnt); !os.IsNotExist(err) {
+			t.Errorf("Target() error = %v; want: %v", err, fs.ErrNotExist)
+		}
+	})
+
 	t.Run("Parallel", func(t *testing.T) {
 		testParallel := func(t *testing.T, de fs.DirEntry, fn func() (fs.FileInfo, error)) {
 			numCPU := runtime.NumCPU()
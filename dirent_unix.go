@@ -5,8 +5,10 @@ package fastwalk
 import (
 	"io/fs"
 	"os"
+	"path/filepath"
 	"sort"
 	"sync"
+	"syscall"
 
 	"github.com/charlievieth/fastwalk/internal/fmtdirent"
 )
@@ -18,6 +20,8 @@ type unixDirent struct {
 	depth  uint32 // uint32 so that we can pack it next to typ
 	info   *fileInfo
 	stat   *fileInfo
+	link   *linkInfo
+	limit  *rateLimiter // Config.MaxStatsPerSecond
 }
 
 func (d *unixDirent) Name() string      { return d.name }
@@ -29,6 +33,7 @@ func (d *unixDirent) String() string    { return fmtdirent.FormatDirEntry(d) }
 func (d *unixDirent) Info() (fs.FileInfo, error) {
 	info := loadFileInfo(&d.info)
 	info.once.Do(func() {
+		d.limit.wait()
 		info.FileInfo, info.err = os.Lstat(d.parent + "/" + d.name)
 	})
 	return info.FileInfo, info.err
@@ -40,11 +45,60 @@ func (d *unixDirent) Stat() (fs.FileInfo, error) {
 	}
 	stat := loadFileInfo(&d.stat)
 	stat.once.Do(func() {
+		d.limit.wait()
 		stat.FileInfo, stat.err = os.Stat(d.parent + "/" + d.name)
 	})
 	return stat.FileInfo, stat.err
 }
 
+func (d *unixDirent) Readlink() (string, error) {
+	if d.typ&os.ModeSymlink == 0 {
+		return "", &os.PathError{Op: "readlink", Path: d.parent + "/" + d.name, Err: syscall.EINVAL}
+	}
+	link := loadLinkInfo(&d.link)
+	link.once.Do(func() {
+		d.limit.wait()
+		link.target, link.err = os.Readlink(d.parent + "/" + d.name)
+	})
+	return link.target, link.err
+}
+
+func (d *unixDirent) Target() (DirEntry, error) {
+	if d.typ&os.ModeSymlink == 0 {
+		return d, nil
+	}
+	target, err := d.Readlink()
+	if err != nil {
+		return nil, err
+	}
+	fi, err := d.Stat()
+	if err != nil {
+		return nil, err
+	}
+	path, err := resolveLink(d.parent+"/"+d.name, target, d.limit)
+	if err != nil {
+		return nil, err
+	}
+	return newTargetDirEntry(path, fi, int(d.depth), d.limit), nil
+}
+
+// newTargetDirEntry returns a DirEntry for the file at path, which is the
+// target of a symbolic link, with FileInfo fi.
+func newTargetDirEntry(path string, fi fs.FileInfo, depth int, limit *rateLimiter) DirEntry {
+	info := &fileInfo{
+		FileInfo: fi,
+	}
+	info.once.Do(func() {})
+	return &unixDirent{
+		parent: filepath.Dir(path),
+		name:   filepath.Base(path),
+		typ:    fi.Mode().Type(),
+		depth:  uint32(depth),
+		info:   info,
+		limit:  limit,
+	}
+}
+
 func newUnixDirent(parent, name string, typ fs.FileMode, depth int) *unixDirent {
 	return &unixDirent{
 		parent: parent,
@@ -67,6 +121,14 @@ func fileInfoToDirEntry(dirname string, fi fs.FileInfo) DirEntry {
 	}
 }
 
+// newDirEntryType returns a DirEntry for the file name in directory parent
+// with type typ. The stat calls it makes are limited by limit.
+func newDirEntryType(parent, name string, typ fs.FileMode, depth int, limit *rateLimiter) DirEntry {
+	de := newUnixDirent(parent, name, typ, depth)
+	de.limit = limit
+	return de
+}
+
 var direntSlicePool = sync.Pool{
 	New: func() any {
 		a := make([]*unixDirent, 0, 32)
@@ -85,6 +147,17 @@ func putDirentSlice(p *[]*unixDirent) {
 	}
 }
 
+// visitSorted sorts dents and visits them with v.
+func (v *direntVisitor) visitSorted(mode SortMode, dents []*unixDirent) error {
+	sortDirents(mode, dents)
+	for _, d := range dents {
+		if err := v.visit(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func sortDirents(mode SortMode, dents []*unixDirent) {
 	if len(dents) <= 1 {
 		return
@@ -0,0 +1,208 @@
+package fastwalk
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// Once more than dispatchThreshold entries of a directory have been read
+// the remaining entries are handed to other workers in batches of
+// dispatchBatchSize, which lets the callbacks for the entries of huge
+// directories run in parallel while the directory is still being read.
+// Sorted directories are not dispatched so their entries are visited in
+// order.
+//
+// These are variables so that tests can lower them.
+var (
+	dispatchThreshold = 8192
+	dispatchBatchSize = 1024
+)
+
+// errDirAborted is returned when reading a directory once the walk has
+// stopped or a batch of the directory dispatched to another worker failed.
+// The error was already handled by the worker that failed.
+var errDirAborted = errors.New("fastwalk: directory aborted")
+
+// Sorted directories are sorted and visited in chunks of sortChunkSize
+// entries to bound memory use. Only the entries within a chunk are ordered
+// relative to each other.
+const sortChunkSize = 64 * 1024
+
+// A direntVisitor passes the entries of the directory being read to
+// walker.onDirEnt and handles ErrSkipFiles. For huge directories it
+// dispatches entries to other workers (see dispatchThreshold).
+type direntVisitor struct {
+	w         *walker
+	dirName   string
+	linkDepth int
+	n         int  // number of entries visited
+	skipFiles bool // ErrSkipFiles returned by a callback of this worker
+	batch     []DirEntry
+	shared    *direntShared // nil until the first batch is dispatched
+}
+
+// direntShared is the state of a directory shared by the workers visiting
+// its entries.
+type direntShared struct {
+	skipFiles atomic.Bool
+	failed    atomic.Bool  // a dispatched batch returned an error
+	inFlight  atomic.Int32 // number of dispatched batches not yet visited
+}
+
+// A direntBatch is a batch of entries of the directory walkItem.dir that
+// are visited by another worker.
+type direntBatch struct {
+	ents   []DirEntry
+	shared *direntShared
+}
+
+func (w *walker) newDirentVisitor(dirName string, linkDepth int) direntVisitor {
+	return direntVisitor{w: w, dirName: dirName, linkDepth: linkDepth}
+}
+
+// skipping reports if the remaining regular files should be skipped.
+func (v *direntVisitor) skipping() bool {
+	return v.skipFiles || (v.shared != nil && v.shared.skipFiles.Load())
+}
+
+// chunked reports if sorted entries should be visited in chunks. Listings
+// read for an ordered walk must be sorted as a whole.
+func (v *direntVisitor) chunked() bool {
+	return v.w.listing == nil
+}
+
+// aborted reports if the walk has stopped or a dispatched batch failed, in
+// which case the remaining entries must not be visited.
+func (v *direntVisitor) aborted() bool {
+	return (v.shared != nil && v.shared.failed.Load()) || v.w.stopped()
+}
+
+// stopped reports if the walk has stopped.
+func (w *walker) stopped() bool {
+	return w.sched != nil && w.sched.stopped.Load()
+}
+
+// visit visits de or, if the directory is large enough, adds it to the
+// batch to dispatch.
+func (v *direntVisitor) visit(de DirEntry) error {
+	if v.aborted() {
+		return errDirAborted
+	}
+	if v.skipping() && de.Type().IsRegular() {
+		return nil
+	}
+	v.n++
+	if v.n > dispatchThreshold && v.canDispatch() {
+		v.batch = append(v.batch, de)
+		if len(v.batch) == dispatchBatchSize {
+			return v.dispatch()
+		}
+		return nil
+	}
+	return v.onDirEnt(de)
+}
+
+func (v *direntVisitor) onDirEnt(de DirEntry) error {
+	err := v.w.onDirEnt(v.dirName, de.Name(), de, v.linkDepth)
+	if err == ErrSkipFiles {
+		v.skipFiles = true
+		if v.shared != nil {
+			v.shared.skipFiles.Store(true)
+		}
+		return nil
+	}
+	return err
+}
+
+func (v *direntVisitor) canDispatch() bool {
+	w := v.w
+	// Batches are not dispatched when checkpointing since they cannot be
+	// saved (see WalkResume), or when sorting since they are visited out
+	// of order.
+	return w.sched != nil && len(w.sched.queues) > 1 && w.sched.ckpt == nil &&
+		w.sortMode == SortNone && w.listing == nil && w.order == nil
+}
+
+// dispatch hands the current batch to another worker. If too many batches
+// are already waiting the batch is visited by the reader instead, which
+// bounds the memory used by a huge directory.
+func (v *direntVisitor) dispatch() error {
+	if v.aborted() {
+		return errDirAborted
+	}
+	if v.shared == nil {
+		v.shared = new(direntShared)
+		if v.skipFiles {
+			v.shared.skipFiles.Store(true)
+		}
+	}
+	sched := v.w.sched
+	if v.shared.inFlight.Load() >= int32(2*len(sched.queues)) {
+		return v.flush()
+	}
+	v.shared.inFlight.Add(1)
+	sched.push(v.w.worker, walkItem{
+		dir:       v.dirName,
+		info:      v.w.cur,
+		linkDepth: v.linkDepth,
+		batch:     &direntBatch{ents: v.batch, shared: v.shared},
+	})
+	v.batch = nil
+	return nil
+}
+
+// flush visits the entries of the current batch.
+func (v *direntVisitor) flush() error {
+	ents := v.batch
+	v.batch = ents[:0]
+	for i, de := range ents {
+		ents[i] = nil
+		if v.aborted() {
+			return errDirAborted
+		}
+		if v.skipping() && de.Type().IsRegular() {
+			continue
+		}
+		if err := v.onDirEnt(de); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finish visits any entries that were not dispatched. It must be called
+// once the directory has been read.
+func (v *direntVisitor) finish() error {
+	if len(v.batch) == 0 {
+		return nil
+	}
+	return v.flush()
+}
+
+// walkBatch visits the entries of a dispatched batch. If it fails the
+// remaining entries of the directory are not visited.
+func (w *walker) walkBatch(it walkItem) error {
+	b := it.batch
+	defer b.shared.inFlight.Add(-1)
+	for _, de := range b.ents {
+		if b.shared.failed.Load() || w.stopped() {
+			return nil
+		}
+		if b.shared.skipFiles.Load() && de.Type().IsRegular() {
+			continue
+		}
+		if err := w.onDirEnt(it.dir, de.Name(), de, it.linkDepth); err != nil {
+			if err == ErrSkipFiles {
+				b.shared.skipFiles.Store(true)
+				continue
+			}
+			b.shared.failed.Store(true)
+			if _, ok := err.(*PanicError); ok {
+				return err
+			}
+			// Report the error for the directory like walk does.
+			return w.fn(it.dir, it.info, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package fastwalk
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// setDispatchSize sets dispatchThreshold and dispatchBatchSize for the
+// duration of the test, which allows testing dispatch without creating
+// directories with thousands of files.
+func setDispatchSize(t testing.TB, threshold, batchSize int) {
+	oldThreshold, oldBatchSize := dispatchThreshold, dispatchBatchSize
+	dispatchThreshold, dispatchBatchSize = threshold, batchSize
+	t.Cleanup(func() {
+		dispatchThreshold, dispatchBatchSize = oldThreshold, oldBatchSize
+	})
+}
+
+// createHugeDir creates a directory containing more than dispatchThreshold
+// files and a few sub-directories.
+func createHugeDir(t testing.TB) (dir string, files int) {
+	dir = t.TempDir()
+	files = dispatchThreshold + 3*dispatchBatchSize + 7
+	for i := 0; i < files; i++ {
+		f, err := os.Create(filepath.Join(dir, "f"+strconv.Itoa(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	for i := 0; i < 4; i++ {
+		sub := filepath.Join(dir, "d"+strconv.Itoa(i))
+		if err := os.Mkdir(sub, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(sub, "a.txt"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, files
+}
+
+func TestDispatchHugeDir(t *testing.T) {
+	t.Run("Small", func(t *testing.T) {
+		setDispatchSize(t, 64, 16)
+		testDispatchHugeDir(t)
+	})
+	t.Run("Default", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("Skipping: short test")
+		}
+		testDispatchHugeDir(t)
+	})
+}
+
+func testDispatchHugeDir(t *testing.T) {
+	dir, files := createHugeDir(t)
+	for _, mode := range []SortMode{SortNone, SortLexical} {
+		t.Run(mode.String(), func(t *testing.T) {
+			var mu sync.Mutex
+			seen := make(map[string]int)
+			var names []string // files in dir in the order visited
+			conf := Config{NumWorkers: 4, Sort: mode}
+			err := Walk(&conf, dir, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				seen[path]++
+				if d.Type().IsRegular() && filepath.Dir(path) == dir {
+					names = append(names, filepath.Base(path))
+				}
+				mu.Unlock()
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// root + files + 4 * (dir + file)
+			if want := 1 + files + 8; len(seen) != want {
+				t.Errorf("visited %d paths; want: %d", len(seen), want)
+			}
+			for path, n := range seen {
+				if n != 1 {
+					t.Errorf("%s: visited %d times", path, n)
+				}
+			}
+			if mode == SortLexical && !sort.StringsAreSorted(names) {
+				t.Error("entries were not visited in sorted order")
+			}
+		})
+	}
+}
+
+func TestDispatchSkipFiles(t *testing.T) {
+	setDispatchSize(t, 64, 16)
+	dir, _ := createHugeDir(t)
+	var mu sync.Mutex
+	var visited []string
+	conf := Config{NumWorkers: 4}
+	err := Walk(&conf, dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		visited = append(visited, path)
+		mu.Unlock()
+		if d.Type().IsRegular() && filepath.Dir(path) == dir {
+			return ErrSkipFiles
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The root, the first file, 4 directories and their files.
+	if len(visited) != 10 {
+		sort.Strings(visited)
+		t.Errorf("visited %d paths; want: 10: %q", len(visited), visited)
+	}
+}
+
+func TestDispatchError(t *testing.T) {
+	setDispatchSize(t, 64, 16)
+	dir, _ := createHugeDir(t)
+	errStop := errors.New("stop")
+	target := filepath.Join(dir, "f"+strconv.Itoa(dispatchThreshold+dispatchBatchSize+1))
+	var mu sync.Mutex
+	var dirErr error
+	conf := Config{NumWorkers: 4}
+	err := Walk(&conf, dir, func(path string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				mu.Lock()
+				dirErr = err
+				mu.Unlock()
+			}
+			return err
+		}
+		if path == target {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("err = %v; want: %v", err, errStop)
+	}
+	// The error is reported for the directory like any other callback error.
+	if dirErr != errStop {
+		t.Errorf("directory err = %v; want: %v", dirErr, errStop)
+	}
+}
+
+// inBatch reports if the caller is visiting a dispatched batch.
+func inBatch() bool {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		f, more := frames.Next()
+		if strings.HasSuffix(f.Function, ".walkBatch") {
+			return true
+		}
+		if !more {
+			return false
+		}
+	}
+}
+
+// Test that the worker reading a directory stops once a batch of the
+// directory that was dispatched to another worker fails.
+func TestDispatchBatchError(t *testing.T) {
+	setDispatchSize(t, 64, 16)
+	dir := t.TempDir()
+	for i := 0; i < 2000; i++ {
+		if err := os.WriteFile(filepath.Join(dir, "f"+strconv.Itoa(i)), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	errStop := errors.New("stop")
+	failed := make(chan struct{})
+	var once sync.Once
+	var readerCalls, after atomic.Int64
+	conf := Config{NumWorkers: 4}
+	err := Walk(&conf, dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		select {
+		case <-failed:
+			after.Add(1)
+			return nil
+		default:
+		}
+		if inBatch() {
+			once.Do(func() { close(failed) })
+			return errStop
+		}
+		// Once entries are being dispatched block the reader until a
+		// batch fails.
+		if readerCalls.Add(1) > int64(dispatchThreshold) {
+			select {
+			case <-failed:
+			case <-time.After(10 * time.Second):
+				t.Error("timed out waiting for a batch to fail")
+			}
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("err = %v; want: %v", err, errStop)
+	}
+	if n := after.Load(); n > int64(dispatchBatchSize) {
+		t.Errorf("walkFn called %d times after a batch failed", n)
+	}
+}
@@ -0,0 +1,69 @@
+package fastwalk
+
+import "errors"
+
+// Encoded EntryFilters start with a magic string followed by a byte that
+// identifies the platform specific FileKey format and the number of keys.
+//
+// The encoding is not portable between operating systems because the
+// FileKey used to identify files differs between them.
+const (
+	entryFilterMagic   = "fwef"
+	entryFilterHdrSize = len(entryFilterMagic) + 1 + 8
+)
+
+// Values of the FileKey format byte
+const (
+	fileKeyUnix    = 1
+	fileKeyWindows = 2
+	fileKeyPath    = 3
+)
+
+var errInvalidEntryFilter = errors.New("fastwalk: invalid EntryFilter encoding")
+
+func appendEntryFilterHeader(b []byte, format byte, n int) []byte {
+	b = append(b, entryFilterMagic...)
+	b = append(b, format)
+	return appendUint64(b, uint64(n))
+}
+
+// parseEntryFilterHeader validates the header of an encoded EntryFilter and
+// returns the number of encoded keys and the remaining data.
+func parseEntryFilterHeader(data []byte, format byte) (int, []byte, error) {
+	if len(data) < entryFilterHdrSize || string(data[:len(entryFilterMagic)]) != entryFilterMagic {
+		return 0, nil, errInvalidEntryFilter
+	}
+	data = data[len(entryFilterMagic):]
+	if data[0] != format {
+		return 0, nil, errors.New("fastwalk: EntryFilter was encoded on a different platform")
+	}
+	n := readUint64(data[1:])
+	data = data[9:]
+	// Each key is at least 8 bytes (the path encoding
+	// stores the length of each path as a uint64).
+	if n > uint64(len(data))/8 {
+		return 0, nil, errInvalidEntryFilter
+	}
+	return int(n), data, nil
+}
+
+// Avoid the dependency on encoding/binary since it pulls in reflect.
+
+func appendUint64(b []byte, v uint64) []byte {
+	return append(b,
+		byte(v),
+		byte(v>>8),
+		byte(v>>16),
+		byte(v>>24),
+		byte(v>>32),
+		byte(v>>40),
+		byte(v>>48),
+		byte(v>>56),
+	)
+}
+
+func readUint64(b []byte) uint64 {
+	_ = b[7] // bounds check hint to compiler
+	return uint64(b[0]) | uint64(b[1])<<8 | uint64(b[2])<<16 | uint64(b[3])<<24 |
+		uint64(b[4])<<32 | uint64(b[5])<<40 | uint64(b[6])<<48 | uint64(b[7])<<56
+}
@@ -0,0 +1,139 @@
+package fastwalk
+
+import (
+	"io/fs"
+	"math"
+	"sync"
+)
+
+// An Entryer records visited files and reports if a file has been seen
+// before. It is implemented by [EntryFilter], [BloomEntryFilter] and
+// [LinkGroups] and is used by the [IgnoreDuplicateDirsFilter] and
+// [IgnoreDuplicateFilesFilter] adapters.
+//
+// Errors that occur while identifying a file are treated as duplicates by
+// [BloomEntryFilter] and [LinkGroups] on all platforms, and by [EntryFilter]
+// on Unix. On Windows and other platforms EntryFilter never treats a file
+// that cannot be identified as a duplicate.
+//
+// Implementations must be safe for concurrent use.
+type Entryer interface {
+	// Entry returns if path and [fs.DirEntry] have been seen before
+	// and records them as seen.
+	Entry(path string, de fs.DirEntry) (seen bool)
+}
+
+var (
+	_ Entryer = (*EntryFilter)(nil)
+	_ Entryer = (*BloomEntryFilter)(nil)
+	_ Entryer = (*LinkGroups)(nil)
+)
+
+type bloomShard struct {
+	mu   sync.Mutex
+	bits []uint64
+}
+
+// A BloomEntryFilter is a memory-bounded alternative to [EntryFilter] that
+// is backed by a sharded bloom filter. It uses a fixed amount of memory
+// that is determined by the expected number of files and the desired false
+// positive rate, which makes it suitable for walks of hundreds of millions
+// of files where the map used by EntryFilter would use gigabytes of memory.
+//
+// A false positive causes Entry to report that a file has been seen when
+// it has not, which means that a file or directory may be skipped. The
+// false positive rate increases when more files than expected are added.
+// Entry never returns a false negative.
+//
+// Errors that occur while identifying a file are treated as duplicates (see
+// [Entryer]).
+type BloomEntryFilter struct {
+	// Like EntryFilter, use multiple shards to reduce lock contention.
+	shards [8]bloomShard
+	nbits  uint64 // number of bits per shard
+	k      uint64 // number of hash functions
+}
+
+// NewBloomEntryFilter returns a new BloomEntryFilter sized to hold n entries
+// with a false positive rate of approximately fpRate. If n is ≤ 0 a size of
+// 1024 is used and if fpRate is not in the range (0, 1) a rate of 0.01 (1%)
+// is used.
+//
+// The filter uses approximately -n*ln(fpRate)/ln(2)² bits of memory,
+// which is about 1.2MB per million entries with a false positive rate
+// of 1%.
+func NewBloomEntryFilter(n int, fpRate float64) *BloomEntryFilter {
+	if n <= 0 {
+		n = 1024
+	}
+	if !(0 < fpRate && fpRate < 1) {
+		fpRate = 0.01
+	}
+	m := math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / float64(n) * math.Ln2)
+	if k < 1 {
+		k = 1
+	}
+
+	b := &BloomEntryFilter{k: uint64(k)}
+	// Round the number of bits in each shard up to a multiple of 64.
+	words := (uint64(m)/uint64(len(b.shards)) + 63) / 64
+	b.nbits = words * 64
+	for i := range b.shards {
+		b.shards[i].bits = make([]uint64, words)
+	}
+	return b
+}
+
+// seen sets the bits for hash h and reports if they were all already set.
+func (b *BloomEntryFilter) seen(h uint64) bool {
+	// Use the high bits to pick the shard and derive the probe
+	// positions using double hashing.
+	s := &b.shards[(h>>61)%uint64(len(b.shards))]
+	h1 := h
+	h2 := mix64(h) | 1
+	seen := true
+	s.mu.Lock()
+	for i := uint64(0); i < b.k; i++ {
+		n := (h1 + i*h2) % b.nbits
+		mask := uint64(1) << (n % 64)
+		if s.bits[n/64]&mask == 0 {
+			s.bits[n/64] |= mask
+			seen = false
+		}
+	}
+	s.mu.Unlock()
+	return seen
+}
+
+// Entry returns if path and [fs.DirEntry] have probably been seen before.
+func (b *BloomEntryFilter) Entry(path string, de fs.DirEntry) (seen bool) {
+	key, err := entryKey(path, de)
+	if err != nil {
+		return true // treat errors as duplicate files
+	}
+	return b.seen(key.hash())
+}
+
+// Reset removes all files from the filter.
+func (b *BloomEntryFilter) Reset() {
+	for i := range b.shards {
+		s := &b.shards[i]
+		s.mu.Lock()
+		for j := range s.bits {
+			s.bits[j] = 0
+		}
+		s.mu.Unlock()
+	}
+}
+
+// mix64 is the finalizer of the SplitMix64 generator and is used
+// to evenly distribute the bits of FileKey hashes.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
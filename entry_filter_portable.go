@@ -8,6 +8,30 @@ import (
 	"sync"
 )
 
+// A FileKey uniquely identifies a file by the path
+// it resolves to after evaluating symlinks.
+type FileKey struct {
+	Path string
+}
+
+func (k FileKey) hash() uint64 {
+	// FNV-1a
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(k.Path); i++ {
+		h ^= uint64(k.Path[i])
+		h *= 1099511628211
+	}
+	return mix64(h)
+}
+
+func entryKey(path string, _ fs.DirEntry) (FileKey, error) {
+	name, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return FileKey{}, err
+	}
+	return FileKey{Path: name}, nil
+}
+
 type EntryFilter struct {
 	// we assume most files have not been seen so
 	// no need for a RWMutex
@@ -32,6 +56,92 @@ func (e *EntryFilter) Entry(path string, _ fs.DirEntry) bool {
 	return ok
 }
 
+// Forget removes the file described by path from the filter and
+// reports if it was present.
+func (e *EntryFilter) Forget(path string, _ fs.DirEntry) bool {
+	name, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	e.mu.Lock()
+	_, ok := e.seen[name]
+	if ok {
+		delete(e.seen, name)
+	}
+	e.mu.Unlock()
+	return ok
+}
+
+// Len returns the number of files recorded by the filter.
+func (e *EntryFilter) Len() int {
+	e.mu.Lock()
+	n := len(e.seen)
+	e.mu.Unlock()
+	return n
+}
+
+// Reset removes all files from the filter.
+func (e *EntryFilter) Reset() {
+	e.mu.Lock()
+	e.seen = nil
+	e.mu.Unlock()
+}
+
+// Keys returns the keys of all files recorded by the filter in
+// no particular order.
+func (e *EntryFilter) Keys() []FileKey {
+	e.mu.Lock()
+	keys := make([]FileKey, 0, len(e.seen))
+	for name := range e.seen {
+		keys = append(keys, FileKey{Path: name})
+	}
+	e.mu.Unlock()
+	return keys
+}
+
+// MarshalBinary implements the [encoding.BinaryMarshaler] interface.
+// The encoding is specific to the operating system the filter was
+// created on.
+func (e *EntryFilter) MarshalBinary() ([]byte, error) {
+	keys := e.Keys()
+	b := make([]byte, 0, entryFilterHdrSize+len(keys)*64)
+	b = appendEntryFilterHeader(b, fileKeyPath, len(keys))
+	for _, k := range keys {
+		b = appendUint64(b, uint64(len(k.Path)))
+		b = append(b, k.Path...)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements the [encoding.BinaryUnmarshaler] interface.
+// It replaces the contents of the filter with the files encoded in data.
+func (e *EntryFilter) UnmarshalBinary(data []byte) error {
+	n, data, err := parseEntryFilterHeader(data, fileKeyPath)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]struct{}, n)
+	for i := 0; i < n; i++ {
+		if len(data) < 8 {
+			return errInvalidEntryFilter
+		}
+		size := readUint64(data)
+		data = data[8:]
+		if size > uint64(len(data)) {
+			return errInvalidEntryFilter
+		}
+		seen[string(data[:size])] = struct{}{}
+		data = data[size:]
+	}
+	if len(data) != 0 {
+		return errInvalidEntryFilter
+	}
+	e.mu.Lock()
+	e.seen = seen
+	e.mu.Unlock()
+	return nil
+}
+
 func NewEntryFilter() *EntryFilter {
 	return &EntryFilter{seen: make(map[string]struct{}, 128)}
 }
@@ -77,6 +77,155 @@ func TestEntryFilter(t *testing.T) {
 	}
 }
 
+func TestEntryFilterLenResetForget(t *testing.T) {
+	tempdir := t.TempDir()
+	var names []string
+	for i := 0; i < 16; i++ {
+		name := filepath.Join(tempdir, fmt.Sprintf("file_%02d", i))
+		if err := writeFile(name, filepath.Base(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	dirEntry := func(t *testing.T, name string) fs.DirEntry {
+		fi, err := os.Lstat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fs.FileInfoToDirEntry(fi)
+	}
+
+	filter := fastwalk.NewEntryFilter()
+	for _, name := range names {
+		filter.Entry(name, dirEntry(t, name))
+	}
+	if n := filter.Len(); n != len(names) {
+		t.Fatalf("Len() = %d; want: %d", n, len(names))
+	}
+	if n := len(filter.Keys()); n != len(names) {
+		t.Fatalf("len(Keys()) = %d; want: %d", n, len(names))
+	}
+
+	if !filter.Forget(names[0], dirEntry(t, names[0])) {
+		t.Error("Forget should return true for a file in the filter")
+	}
+	if filter.Forget(names[0], dirEntry(t, names[0])) {
+		t.Error("Forget should return false for a file not in the filter")
+	}
+	if n := filter.Len(); n != len(names)-1 {
+		t.Errorf("Len() = %d; want: %d", n, len(names)-1)
+	}
+	if filter.Entry(names[0], dirEntry(t, names[0])) {
+		t.Error("forgotten file should not be seen")
+	}
+
+	filter.Reset()
+	if n := filter.Len(); n != 0 {
+		t.Errorf("Len() = %d; want: %d", n, 0)
+	}
+	if filter.Entry(names[1], dirEntry(t, names[1])) {
+		t.Error("file should not be seen after Reset")
+	}
+}
+
+func TestEntryFilterMarshalBinary(t *testing.T) {
+	tempdir := t.TempDir()
+	var names []string
+	for i := 0; i < 16; i++ {
+		name := filepath.Join(tempdir, fmt.Sprintf("file_%02d", i))
+		if err := writeFile(name, filepath.Base(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	des := make([]fs.DirEntry, len(names))
+	for i, name := range names {
+		fi, err := os.Lstat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		des[i] = fs.FileInfoToDirEntry(fi)
+	}
+
+	filter := fastwalk.NewEntryFilter()
+	for i := 0; i < len(names)/2; i++ {
+		filter.Entry(names[i], des[i])
+	}
+	data, err := filter.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := fastwalk.NewEntryFilter()
+	restored.Entry(names[len(names)-1], des[len(names)-1]) // replaced by UnmarshalBinary
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Len() != filter.Len() {
+		t.Fatalf("Len() = %d; want: %d", restored.Len(), filter.Len())
+	}
+	for i, name := range names {
+		want := i < len(names)/2
+		if got := restored.Entry(name, des[i]); got != want {
+			t.Errorf("%s: Entry() = %t; want: %t", filepath.Base(name), got, want)
+		}
+	}
+
+	for _, data := range [][]byte{
+		nil,
+		[]byte("bad"),
+		data[:len(data)-1],
+	} {
+		if err := fastwalk.NewEntryFilter().UnmarshalBinary(data); err == nil {
+			t.Errorf("UnmarshalBinary(%q): expected an error", data)
+		}
+	}
+}
+
+func TestBloomEntryFilter(t *testing.T) {
+	tempdir := t.TempDir()
+	const numFiles = 256
+	names := make([]string, numFiles)
+	des := make([]fs.DirEntry, numFiles)
+	for i := range names {
+		name := filepath.Join(tempdir, fmt.Sprintf("file_%03d", i))
+		if err := writeFile(name, filepath.Base(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		fi, err := os.Lstat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names[i] = name
+		des[i] = fs.FileInfoToDirEntry(fi)
+	}
+
+	filter := fastwalk.NewBloomEntryFilter(numFiles, 0.0001)
+	falsePositives := 0
+	for i, name := range names {
+		if filter.Entry(name, des[i]) {
+			falsePositives++
+		}
+	}
+	// With a false positive rate of 0.01% we should not see more than one.
+	if falsePositives > 1 {
+		t.Errorf("too many false positives: %d", falsePositives)
+	}
+	for i, name := range names {
+		if !filter.Entry(name, des[i]) {
+			t.Errorf("false negative: %s", filepath.Base(name))
+		}
+	}
+
+	filter.Reset()
+	if filter.Entry(names[0], des[0]) {
+		t.Error("file should not be seen after Reset")
+	}
+	if !filter.Entry(filepath.Join(tempdir, "nonexistent"), nil) {
+		t.Error("BloomEntryFilter should treat errors as duplicates")
+	}
+}
+
 func BenchmarkEntryFilter(b *testing.B) {
 	tempdir := b.TempDir()
 
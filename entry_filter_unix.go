@@ -8,14 +8,15 @@ import (
 	"syscall"
 )
 
-type fileKey struct {
+// A FileKey uniquely identifies a file by its device and inode number.
+type FileKey struct {
 	Dev uint64
 	Ino uint64
 }
 
 type entryMap struct {
 	mu   sync.Mutex
-	keys map[fileKey]struct{}
+	keys map[FileKey]struct{}
 }
 
 // An EntryFilter keeps track of visited directory entries and can be used to
@@ -33,27 +34,129 @@ func NewEntryFilter() *EntryFilter {
 	return new(EntryFilter)
 }
 
+func (e *EntryFilter) entryMap(key FileKey) *entryMap {
+	return &e.ents[key.Ino%uint64(len(e.ents))]
+}
+
 func (e *EntryFilter) seen(dev, ino uint64) (seen bool) {
-	m := &e.ents[ino%uint64(len(e.ents))]
+	key := FileKey{dev, ino}
+	m := e.entryMap(key)
 	m.mu.Lock()
-	if _, seen = m.keys[fileKey{dev, ino}]; !seen {
+	if _, seen = m.keys[key]; !seen {
 		if m.keys == nil {
-			m.keys = make(map[fileKey]struct{})
+			m.keys = make(map[FileKey]struct{})
 		}
-		m.keys[fileKey{dev, ino}] = struct{}{}
+		m.keys[key] = struct{}{}
 	}
 	m.mu.Unlock()
 	return seen
 }
 
+func (k FileKey) hash() uint64 {
+	return mix64(k.Dev*0x9e3779b97f4a7c15 ^ k.Ino)
+}
+
+func entryKey(path string, de fs.DirEntry) (FileKey, error) {
+	fi, err := StatDirEntry(path, de)
+	if err != nil {
+		return FileKey{}, err
+	}
+	stat := fi.Sys().(*syscall.Stat_t)
+	return FileKey{Dev: uint64(stat.Dev), Ino: uint64(stat.Ino)}, nil
+}
+
 // TODO: this name is confusing and should be fixed
 
 // Entry returns if path and [fs.DirEntry] have been seen before.
 func (e *EntryFilter) Entry(path string, de fs.DirEntry) (seen bool) {
-	fi, err := StatDirEntry(path, de)
+	key, err := entryKey(path, de)
 	if err != nil {
 		return true // treat errors as duplicate files
 	}
-	stat := fi.Sys().(*syscall.Stat_t)
-	return e.seen(uint64(stat.Dev), uint64(stat.Ino))
+	return e.seen(key.Dev, key.Ino)
+}
+
+// Forget removes the file described by path and [fs.DirEntry] from the
+// filter and reports if it was present.
+func (e *EntryFilter) Forget(path string, de fs.DirEntry) bool {
+	key, err := entryKey(path, de)
+	if err != nil {
+		return false
+	}
+	m := e.entryMap(key)
+	m.mu.Lock()
+	_, ok := m.keys[key]
+	if ok {
+		delete(m.keys, key)
+	}
+	m.mu.Unlock()
+	return ok
+}
+
+// Len returns the number of files recorded by the filter.
+func (e *EntryFilter) Len() int {
+	n := 0
+	for i := range e.ents {
+		m := &e.ents[i]
+		m.mu.Lock()
+		n += len(m.keys)
+		m.mu.Unlock()
+	}
+	return n
+}
+
+// Reset removes all files from the filter.
+func (e *EntryFilter) Reset() {
+	for i := range e.ents {
+		m := &e.ents[i]
+		m.mu.Lock()
+		m.keys = nil
+		m.mu.Unlock()
+	}
+}
+
+// Keys returns the keys of all files recorded by the filter in
+// no particular order.
+func (e *EntryFilter) Keys() []FileKey {
+	keys := make([]FileKey, 0, e.Len())
+	for i := range e.ents {
+		m := &e.ents[i]
+		m.mu.Lock()
+		for k := range m.keys {
+			keys = append(keys, k)
+		}
+		m.mu.Unlock()
+	}
+	return keys
+}
+
+// MarshalBinary implements the [encoding.BinaryMarshaler] interface.
+// The encoding is specific to the operating system the filter was
+// created on.
+func (e *EntryFilter) MarshalBinary() ([]byte, error) {
+	keys := e.Keys()
+	b := make([]byte, 0, entryFilterHdrSize+len(keys)*16)
+	b = appendEntryFilterHeader(b, fileKeyUnix, len(keys))
+	for _, k := range keys {
+		b = appendUint64(b, k.Dev)
+		b = appendUint64(b, k.Ino)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements the [encoding.BinaryUnmarshaler] interface.
+// It replaces the contents of the filter with the files encoded in data.
+func (e *EntryFilter) UnmarshalBinary(data []byte) error {
+	n, data, err := parseEntryFilterHeader(data, fileKeyUnix)
+	if err != nil {
+		return err
+	}
+	if len(data) != n*16 {
+		return errInvalidEntryFilter
+	}
+	e.Reset()
+	for ; len(data) != 0; data = data[16:] {
+		e.seen(readUint64(data), readUint64(data[8:]))
+	}
+	return nil
 }
@@ -57,6 +57,46 @@ func TestEntryFilter_Unix(t *testing.T) {
 	}
 }
 
+func TestEntryFilter_Unix_Keys(t *testing.T) {
+	rr := rand.New(rand.NewSource(1))
+	pairs := generateDevIno(rr, 2, 100)
+
+	x := NewEntryFilter()
+	for _, p := range pairs {
+		x.seen(p.Dev, p.Ino)
+	}
+	want := make(map[FileKey]bool, len(pairs))
+	for _, p := range pairs {
+		want[FileKey{Dev: p.Dev, Ino: p.Ino}] = true
+	}
+	keys := x.Keys()
+	if len(keys) != len(want) {
+		t.Fatalf("len(Keys()) = %d; want: %d", len(keys), len(want))
+	}
+	for _, k := range keys {
+		if !want[k] {
+			t.Errorf("unexpected key: %+v", k)
+		}
+	}
+
+	data, err := x.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	y := NewEntryFilter()
+	if err := y.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range pairs {
+		if !y.seen(p.Dev, p.Ino) {
+			t.Errorf("missing: Dev: %d Ino: %d", p.Dev, p.Ino)
+		}
+	}
+	if y.Len() != len(pairs) {
+		t.Errorf("Len() = %d; want: %d", y.Len(), len(pairs))
+	}
+}
+
 func TestEntryFilter_Unix_Parallel(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Short test")
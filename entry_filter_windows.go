@@ -10,7 +10,9 @@ import (
 	"syscall"
 )
 
-type fileKey struct {
+// A FileKey uniquely identifies a file by its volume serial number
+// and file index.
+type FileKey struct {
 	VolumeSerialNumber uint32
 	FileIndexHigh      uint32
 	FileIndexLow       uint32
@@ -18,51 +20,156 @@ type fileKey struct {
 
 type EntryFilter struct {
 	mu   sync.Mutex
-	seen map[fileKey]struct{}
+	seen map[FileKey]struct{}
 }
 
 func NewEntryFilter() *EntryFilter {
-	return &EntryFilter{seen: make(map[fileKey]struct{}, 128)}
+	return &EntryFilter{seen: make(map[FileKey]struct{}, 128)}
 }
 
-func (e *EntryFilter) Entry(path string, _ fs.DirEntry) bool {
+func entryKey(path string, _ fs.DirEntry) (FileKey, error) {
 	namep, err := syscall.UTF16PtrFromString(fixLongPath(path))
 	if err != nil {
-		return false
+		return FileKey{}, err
 	}
 
 	h, err := syscall.CreateFile(namep, 0, 0, nil, syscall.OPEN_EXISTING,
 		syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
 	if err != nil {
-		return false
+		return FileKey{}, err
 	}
 
 	var d syscall.ByHandleFileInformation
 	err = syscall.GetFileInformationByHandle(h, &d)
 	syscall.CloseHandle(h)
 	if err != nil {
-		return false
+		return FileKey{}, err
 	}
 
-	key := fileKey{
+	return FileKey{
 		VolumeSerialNumber: d.VolumeSerialNumber,
 		FileIndexHigh:      d.FileIndexHigh,
 		FileIndexLow:       d.FileIndexLow,
-	}
+	}, nil
+}
+
+func (k FileKey) hash() uint64 {
+	ino := uint64(k.FileIndexHigh)<<32 | uint64(k.FileIndexLow)
+	return mix64(uint64(k.VolumeSerialNumber)*0x9e3779b97f4a7c15 ^ ino)
+}
 
+func (e *EntryFilter) add(key FileKey) bool {
 	e.mu.Lock()
 	if e.seen == nil {
-		e.seen = make(map[fileKey]struct{})
+		e.seen = make(map[FileKey]struct{})
 	}
 	_, ok := e.seen[key]
 	if !ok {
 		e.seen[key] = struct{}{}
 	}
 	e.mu.Unlock()
+	return ok
+}
 
+func (e *EntryFilter) Entry(path string, de fs.DirEntry) bool {
+	key, err := entryKey(path, de)
+	if err != nil {
+		return false
+	}
+	return e.add(key)
+}
+
+// Forget removes the file described by path from the filter and
+// reports if it was present.
+func (e *EntryFilter) Forget(path string, de fs.DirEntry) bool {
+	key, err := entryKey(path, de)
+	if err != nil {
+		return false
+	}
+	e.mu.Lock()
+	_, ok := e.seen[key]
+	if ok {
+		delete(e.seen, key)
+	}
+	e.mu.Unlock()
 	return ok
 }
 
+// Len returns the number of files recorded by the filter.
+func (e *EntryFilter) Len() int {
+	e.mu.Lock()
+	n := len(e.seen)
+	e.mu.Unlock()
+	return n
+}
+
+// Reset removes all files from the filter.
+func (e *EntryFilter) Reset() {
+	e.mu.Lock()
+	e.seen = nil
+	e.mu.Unlock()
+}
+
+// Keys returns the keys of all files recorded by the filter in
+// no particular order.
+func (e *EntryFilter) Keys() []FileKey {
+	e.mu.Lock()
+	keys := make([]FileKey, 0, len(e.seen))
+	for k := range e.seen {
+		keys = append(keys, k)
+	}
+	e.mu.Unlock()
+	return keys
+}
+
+// MarshalBinary implements the [encoding.BinaryMarshaler] interface.
+// The encoding is specific to the operating system the filter was
+// created on.
+func (e *EntryFilter) MarshalBinary() ([]byte, error) {
+	keys := e.Keys()
+	b := make([]byte, 0, entryFilterHdrSize+len(keys)*12)
+	b = appendEntryFilterHeader(b, fileKeyWindows, len(keys))
+	for _, k := range keys {
+		b = appendUint32(b, k.VolumeSerialNumber)
+		b = appendUint32(b, k.FileIndexHigh)
+		b = appendUint32(b, k.FileIndexLow)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements the [encoding.BinaryUnmarshaler] interface.
+// It replaces the contents of the filter with the files encoded in data.
+func (e *EntryFilter) UnmarshalBinary(data []byte) error {
+	n, data, err := parseEntryFilterHeader(data, fileKeyWindows)
+	if err != nil {
+		return err
+	}
+	if len(data) != n*12 {
+		return errInvalidEntryFilter
+	}
+	seen := make(map[FileKey]struct{}, n)
+	for ; len(data) != 0; data = data[12:] {
+		seen[FileKey{
+			VolumeSerialNumber: readUint32(data),
+			FileIndexHigh:      readUint32(data[4:]),
+			FileIndexLow:       readUint32(data[8:]),
+		}] = struct{}{}
+	}
+	e.mu.Lock()
+	e.seen = seen
+	e.mu.Unlock()
+	return nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func readUint32(b []byte) uint32 {
+	_ = b[3] // bounds check hint to compiler
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
+
 func isAbs(path string) (b bool) {
 	v := filepath.VolumeName(path)
 	if v == "" {
@@ -8,11 +8,12 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 
 	"github.com/charlievieth/fastwalk"
 )
 
-const usageMsg = `Usage: %[1]s [-L] [-name] [PATH...]:
+const usageMsg = `Usage: %[1]s [-L] [-name] [-regex] [PATH...]:
 
 %[1]s is a poor replacement for the POSIX find utility
 
@@ -24,6 +25,7 @@ func main() {
 		flag.PrintDefaults()
 	}
 	pattern := flag.String("name", "", "Pattern to match file names against.")
+	regex := flag.String("regex", "", "Regular expression to match paths (relative to PATH) against.")
 	followLinks := flag.Bool("L", false, "Follow symbolic links")
 	flag.Parse()
 
@@ -37,6 +39,14 @@ func main() {
 	conf := fastwalk.Config{
 		Follow: *followLinks,
 	}
+	if *regex != "" {
+		re, err := regexp.Compile(*regex)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid regex: %v\n", err)
+			os.Exit(1)
+		}
+		conf.Match = re
+	}
 
 	walkFn := func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
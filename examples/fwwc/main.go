@@ -42,6 +42,20 @@ func countLinesInFile(name string) (int64, error) {
 }
 
 func LineCount(root string, followLinks bool) error {
+	// Count lines using a separate pool of goroutines so that reading files
+	// does not stall the goroutines that read directories. The pool is
+	// bounded so at most DefaultNumWorkers files are open at once.
+	proc := fastwalk.NewProcessor(fastwalk.DefaultNumWorkers(), func(path string, _ fs.DirEntry) error {
+		lines, err := countLinesInFile(path)
+		if err == nil {
+			fmt.Printf("%8d %s\n", lines, path)
+		} else {
+			// Print but do not return the error.
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+		}
+		return nil
+	})
+
 	countLinesWalkFn := func(path string, d fs.DirEntry, err error) error {
 		// We wrap this with fastwalk.IgnorePermissionErrors so we know the
 		// error is not a permission error (common when walking outside a users
@@ -63,31 +77,29 @@ func LineCount(root string, followLinks bool) error {
 			}
 		}
 
-		// Skip dot (".") files (but allow "." / PWD as the path)
-		if path != "." && typ.IsDir() {
-			name := d.Name()
-			if name == "" || name[0] == '.' || name[0] == '_' {
+		// Skip directories that start with an underscore ("_"). Hidden
+		// directories are skipped by fastwalk.IgnoreHiddenWith.
+		if typ.IsDir() {
+			if name := d.Name(); path != root && name != "" && name[0] == '_' {
 				return fastwalk.SkipDir
 			}
 			return nil
 		}
 		if typ.IsRegular() {
-			lines, err := countLinesInFile(path)
-			if err == nil {
-				fmt.Printf("%8d %s\n", lines, path)
-			} else {
-				// Print but do not return the error.
-				fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
-			}
+			return proc.Submit(path, d)
 		}
 		return nil
 	}
 
-	// Ignore permission errors traversing directories.
+	// Ignore permission errors traversing directories and skip hidden
+	// directories (but not the root, even if it is ".").
 	//
 	// Note: this only ignores permission errors when traversing directories.
 	// Permission errors may still be encountered when accessing files.
-	walkFn := fastwalk.IgnorePermissionErrors(countLinesWalkFn)
+	walkFn := fastwalk.Chain(countLinesWalkFn,
+		fastwalk.IgnorePermissionErrors,
+		fastwalk.IgnoreHiddenWith(fastwalk.IgnoreHiddenOptions{DirsOnly: true}),
+	)
 
 	conf := fastwalk.Config{
 		// Safely follow symbolic links. This can also be achieved by
@@ -99,7 +111,11 @@ func LineCount(root string, followLinks bool) error {
 	}
 	// Note: Walk can also be called with a nil Config, in which case
 	// fastwalk.DefaultConfig is used.
-	if err := fastwalk.Walk(&conf, root, walkFn); err != nil {
+	err := fastwalk.Walk(&conf, root, walkFn)
+	if perr := proc.Wait(); err == nil {
+		err = perr
+	}
+	if err != nil {
 		return fmt.Errorf("walking directory %s: %w", root, err)
 	}
 	return nil
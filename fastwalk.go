@@ -41,8 +41,11 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // ErrTraverseLink is used as a return value from WalkDirFuncs to indicate that
@@ -60,6 +63,16 @@ var ErrSkipFiles = errors.New("fastwalk: skip remaining files in directory")
 // as an error by any function.
 var SkipDir = fs.SkipDir
 
+// ErrMaxSymlinkDepth is passed to the WalkDirFunc when traversing a symbolic
+// link would exceed the MaxSymlinkDepth [Config] limit. The error is wrapped
+// in an [*os.PathError] and should be checked for with [errors.Is].
+var ErrMaxSymlinkDepth = errors.New("fastwalk: maximum symlink depth exceeded")
+
+// ErrMaxFollowedLinks is passed to the WalkDirFunc when traversing a symbolic
+// link would exceed the MaxFollowedLinks [Config] limit. The error is wrapped
+// in an [*os.PathError] and should be checked for with [errors.Is].
+var ErrMaxFollowedLinks = errors.New("fastwalk: maximum number of followed symlinks exceeded")
+
 // TODO(charlie): Look into implementing the fs.SkipAll behavior of
 // filepath.Walk and filepath.WalkDir. This may not be possible without taking
 // a performance hit.
@@ -70,14 +83,22 @@ var SkipDir = fs.SkipDir
 // (10 cores or less), or 10 (more than 10 cores). This is because Walk / IO
 // performance on Darwin degrades with more concurrency.
 //
+// On Linux the number of CPUs is further limited by the CPU quota of the
+// process's cgroup (cpu.max with cgroup v2 or cpu.cfs_quota_us with v1),
+// since in containers GOMAXPROCS may reflect the host rather than the
+// container's CPU limit.
+//
 // The optimal number for your workload may be lower or higher. The results
 // of BenchmarkFastWalkNumWorkers benchmark may be informative.
 func DefaultNumWorkers() int {
 	numCPU := runtime.GOMAXPROCS(-1)
+	if n := cgroupCPULimit(); n > 0 && n < numCPU {
+		numCPU = n
+	}
 	if numCPU < 4 {
 		return 4
 	}
-	// User manually set GOMAXPROCS - respect it.
+	// User manually set GOMAXPROCS or the cgroup CPU quota is lower - respect it.
 	if numCPU != runtime.NumCPU() {
 		return min(numCPU, 32)
 	}
@@ -165,7 +186,8 @@ func DefaultToSlash() bool {
 // SortMode determines the order that a directory's entries are visited by
 // [Walk]. Sorting applies only at the directory level and since we process
 // directories in parallel the order in which all files are visited is still
-// non-deterministic.
+// non-deterministic. [List] and [ListSorted] can be used to visit all entries
+// in a deterministic order.
 //
 // Sorting is mostly useful for programs that print the output of Walk since
 // it makes it slightly more ordered compared to the default directory order.
@@ -175,6 +197,10 @@ func DefaultToSlash() bool {
 //
 // All lexical sorting is case-sensitive.
 //
+// To bound memory use the entries of very large directories (more than
+// 65536 entries) are sorted and visited in chunks, so their order is only
+// guaranteed within each chunk. This does not apply to [Config.Ordered].
+//
 // The overhead of sorting is minimal compared to the syscalls needed to
 // walk directories. The impact on performance due to changing the order
 // in which directory entries are processed will be dependent on the workload
@@ -255,6 +281,52 @@ func (s SortMode) String() string {
 	return "SortMode(" + itoa(uint64(s)) + ")"
 }
 
+// Strategy determines the order in which [Walk] traverses directories.
+//
+// Each worker has its own queue of directories that it found and idle
+// workers take the directories closest to the root from the queues of other
+// workers, so the order is only approximately the one described by the
+// Strategy when more than one worker is used.
+type Strategy uint32
+
+const (
+	// Visit the most recently found directory first. This keeps the
+	// number of queued directories small, since they are bounded by the
+	// depth of the tree instead of its width. This is the default.
+	DepthFirst Strategy = iota
+
+	// Visit directories in the order that they were found, so that the
+	// directories closest to the root are visited first. This is useful for
+	// interactive programs that want shallow results to appear first, but
+	// the number of queued directories may grow as large as the widest
+	// level of the tree.
+	BreadthFirst
+
+	// Visit directories breadth-first until the number of pending
+	// directories reaches a limit (256 times the number of workers) and
+	// then depth-first until it drops below the limit. This provides most
+	// of the benefit of BreadthFirst while bounding memory use.
+	Hybrid
+)
+
+// hybridQueueLimit multiplied by the number of workers is the number of
+// pending directories, across all workers, at which the Hybrid strategy
+// switches from breadth-first to depth-first traversal.
+const hybridQueueLimit = 256
+
+var strategyStrs = [...]string{
+	DepthFirst:   "DepthFirst",
+	BreadthFirst: "BreadthFirst",
+	Hybrid:       "Hybrid",
+}
+
+func (s Strategy) String() string {
+	if 0 <= int(s) && int(s) < len(strategyStrs) {
+		return strategyStrs[s]
+	}
+	return "Strategy(" + itoa(uint64(s)) + ")"
+}
+
 // DefaultConfig is the default [Config] used when none is supplied.
 var DefaultConfig = Config{
 	Follow:     false,
@@ -304,14 +376,120 @@ type Config struct {
 	// nicer compared to the default directory order, which is basically random.
 	Sort SortMode
 
+	// Ordered causes the WalkDirFunc to be called serially, by a single
+	// goroutine, in depth-first order with the entries of each directory
+	// sorted by Sort ([SortNone] is treated as [SortLexical]). With lexical
+	// sorting this is the same order as [filepath.WalkDir], which makes the
+	// output of Walk stable between runs.
+	//
+	// Directories are still read in parallel: the workers read ahead of the
+	// callback and buffer a bounded number of directory listings until they
+	// are needed. Since the callback is serial, the speedup is smaller when
+	// the callback is expensive.
+	//
+	// When Ordered is true, returning [filepath.SkipDir] for a file skips the
+	// remaining entries in the file's directory, as it does with WalkDir.
+	Ordered bool
+
+	// Strategy determines the order in which directories are traversed.
+	// It has no effect when Ordered is true.
+	Strategy Strategy
+
+	// MaxQueuedDirs limits the number of directories that are held in
+	// memory while waiting to be walked. Once the limit is reached, the
+	// paths of additional directories are written to a temporary file (in
+	// the directory returned by os.TempDir) and read back as the queue
+	// drains. This bounds the memory used when walking trees with a very
+	// large number of sibling directories.
+	//
+	// A value of zero or less disables this feature. It has no effect when
+	// Ordered is true.
+	MaxQueuedDirs int
+
+	// AutoTune adjusts the number of workers while walking based on the
+	// measured rate at which directories are read. The walk starts with two
+	// workers and adds more for as long as doing so increases throughput,
+	// up to NumWorkers, which makes the worker count adapt to the storage
+	// being walked (for example: a laptop SSD, NVMe array or NFS share).
+	// Workers are removed if the time to read a directory degrades.
+	//
+	// It has no effect when Ordered is true.
+	AutoTune bool
+
+	// MaxDirsPerSecond limits the rate at which directories are read and
+	// MaxStatsPerSecond limits the rate of the lstat(2), stat(2) and
+	// readlink(2) calls made by the walk, which include the calls made to
+	// follow symlinks and to check them for loops, and by the methods of
+	// the DirEntry passed to the WalkDirFunc. This can be used to prevent
+	// background walks from saturating shared storage.
+	// Short bursts of up to one second's worth of operations are allowed.
+	//
+	// A value of zero or less means no limit.
+	MaxDirsPerSecond  int
+	MaxStatsPerSecond int
+
+	// Throttle, if not nil, is called before each directory is read and may
+	// block to pause the walk. Like ionice(1) it can be used to yield to
+	// other programs, for example by sleeping while the system is busy.
+	Throttle Throttler
+
 	// Number of parallel workers to use. If NumWorkers if ≤ 0 then
-	// DefaultNumWorkers is used.
+	// DefaultNumWorkers is used. When AutoTune is true this is the
+	// maximum number of workers.
 	NumWorkers int
 
 	// MaxDepth limits the depth of directory traversal to MaxDepth levels
 	// beyond the root directory being walked. By default, there is no limit
 	// on the search depth and a value of zero or less disables this feature.
 	MaxDepth int
+
+	// MaxSymlinkDepth limits the number of symbolic links that may be
+	// traversed in a single chain from the root to a directory. Loop
+	// detection alone does not prevent link farms (like a Nix store or
+	// a node_modules directory created by pnpm) from multiplying the size
+	// of the walked tree.
+	//
+	// When traversing a symlink would exceed the limit, the WalkDirFunc is
+	// called a second time for the symlink with an error that wraps
+	// ErrMaxSymlinkDepth and the symlink is not traversed. A value of zero
+	// or less disables this feature.
+	MaxSymlinkDepth int
+
+	// MaxFollowedLinks limits the total number of symbolic links that are
+	// traversed during the walk.
+	//
+	// When traversing a symlink would exceed the limit, the WalkDirFunc is
+	// called a second time for the symlink with an error that wraps
+	// ErrMaxFollowedLinks and the symlink is not traversed. A value of zero
+	// or less disables this feature.
+	MaxFollowedLinks int
+
+	// Match, if not nil, limits the entries passed to the WalkDirFunc to
+	// those with a path relative to the root that matches the regular
+	// expression. The relative path always uses forward slashes "/" as
+	// the separator and the relative path of the root is ".". Directories
+	// that do not match are still traversed and errors are always reported.
+	//
+	// Literal prefixes (e.g. "^src/"), suffixes (e.g. `\.go$`) and substrings
+	// required by the expression are checked before running the regular
+	// expression engine so that most entries are rejected cheaply.
+	Match *regexp.Regexp
+
+	// Prune, if not nil, causes entries with a relative path (see Match)
+	// that matches the regular expression to be ignored. Pruned entries are
+	// not passed to the WalkDirFunc and pruned directories are not traversed
+	// (for example: `(^|/)(node_modules|\.git)$`). This is more efficient
+	// than returning SkipDir from the WalkDirFunc since pruned directories
+	// are never enqueued. The root is never pruned.
+	Prune *regexp.Regexp
+
+	// RecoverPanics causes panics in the WalkDirFunc to be recovered.
+	// When the WalkDirFunc panics the walk is stopped and Walk returns
+	// a *PanicError that records the path, the value passed to panic
+	// and the stack trace of the panicking goroutine. Otherwise, since
+	// the WalkDirFunc is called from a goroutine started by Walk, a panic
+	// crashes the program.
+	RecoverPanics bool
 }
 
 // Copy returns a copy of c. If c is nil an empty [Config] is returned.
@@ -344,6 +522,29 @@ type DirEntry interface {
 	Depth() int
 }
 
+// A SymlinkDirEntry is a [DirEntry] that can report where a symbolic link
+// points. The result of Readlink() is cached.
+//
+// The [DirEntry] argument passed to the [fs.WalkDirFunc] by [Walk] is always
+// a SymlinkDirEntry. The [Readlink] and [Target] helper functions can be
+// used with any [fs.DirEntry].
+type SymlinkDirEntry interface {
+	DirEntry
+
+	// Readlink returns the destination of the symbolic link described by the
+	// entry (see [os.Readlink]). If the entry is not a symbolic link an
+	// [*os.PathError] is returned.
+	Readlink() (string, error)
+
+	// Target returns a DirEntry describing the file that the symbolic link
+	// described by the entry ultimately refers to, after following any
+	// chain of links. Its Name is the base name of that file and its Type,
+	// Info and Stat methods describe it. If the link is dangling the error
+	// returned by Stat is returned. If the entry is not a symbolic link the
+	// entry itself is returned.
+	Target() (DirEntry, error)
+}
+
 // Walk is a faster implementation of [filepath.WalkDir] that walks the file
 // tree rooted at root in parallel, calling walkFn for each file or directory
 // in the tree, including root.
@@ -412,6 +613,26 @@ type DirEntry interface {
 //   - The [fs.SkipAll] sentinel error is not respected and not ignored. If the
 //     WalkDirFunc returns SkipAll then Walk will exit with the error SkipAll.
 func Walk(conf *Config, root string, walkFn fs.WalkDirFunc) error {
+	return walkTree(conf, root, walkFn, nil)
+}
+
+// walkOptions are the options of walkTree that are not part of Config.
+type walkOptions struct {
+	// newWalkFn, if not nil, is called once by each worker goroutine to
+	// create the callback used by that worker, in which case walkFn is not
+	// used and may be nil.
+	newWalkFn func() fs.WalkDirFunc
+
+	handle     *Walker // used to pause or stop the walk (see Start)
+	checkpoint string  // checkpoint file (see WalkResume)
+}
+
+// walkTree implements Walk. The opts may be nil.
+func walkTree(conf *Config, root string, walkFn fs.WalkDirFunc, opts *walkOptions) error {
+	if opts == nil {
+		opts = new(walkOptions)
+	}
+	newWalkFn := opts.newWalkFn
 	fi, err := os.Stat(root)
 	if err != nil {
 		return err
@@ -423,12 +644,15 @@ func Walk(conf *Config, root string, walkFn fs.WalkDirFunc) error {
 	if conf.ToSlash {
 		root = filepath.ToSlash(root)
 	}
-
-	// Make sure to wait for all workers to finish, otherwise
-	// walkFn could still be called after returning. This Wait call
-	// runs after close(e.donec) below.
-	var wg sync.WaitGroup
-	defer wg.Wait()
+	if conf.RecoverPanics {
+		walkFn = recoverWalkFunc(walkFn)
+		if newWalkFn != nil {
+			newFn := newWalkFn
+			newWalkFn = func() fs.WalkDirFunc {
+				return recoverWalkFunc(newFn())
+			}
+		}
+	}
 
 	numWorkers := conf.NumWorkers
 	if numWorkers <= 0 {
@@ -436,122 +660,160 @@ func Walk(conf *Config, root string, walkFn fs.WalkDirFunc) error {
 	}
 
 	w := &walker{
-		fn: walkFn,
-		// TODO: Increase the size of enqueuec so that we don't stall
-		// while processing a directory. Increasing the size of workc
-		// doesn't help as much (needs more testing).
-		enqueuec: make(chan walkItem, numWorkers), // buffered for performance
-		workc:    make(chan walkItem, numWorkers), // buffered for performance
-		donec:    make(chan struct{}),
-
-		// buffered for correctness & not leaking goroutines:
-		resc: make(chan error, numWorkers),
+		fn:    walkFn,
+		newFn: newWalkFn,
 
 		// TODO: we should just pass the Config
-		maxDepth: conf.MaxDepth,
-		follow:   conf.Follow,
-		toSlash:  conf.ToSlash,
-		sortMode: conf.Sort,
+		maxDepth:     conf.MaxDepth,
+		maxLinkDepth: conf.MaxSymlinkDepth,
+		maxFollowed:  int64(conf.MaxFollowedLinks),
+		followed:     new(atomic.Int64),
+		follow:       conf.Follow,
+		toSlash:      conf.ToSlash,
+		sortMode:     conf.Sort,
+		match:        newPathMatcher(conf.Match),
+		prune:        newPathMatcher(conf.Prune),
+		dirLimit:     newRateLimiter(conf.MaxDirsPerSecond),
+		statLimit:    newRateLimiter(conf.MaxStatsPerSecond),
+		throttler:    conf.Throttle,
+		handle:       opts.handle,
 	}
 	if w.follow {
 		w.ignoredDirs = append(w.ignoredDirs, fi)
 	}
 
-	defer close(w.donec)
+	root = cleanRootPath(root)
+	w.root = root
+	rootMatch, _ := w.matchPath(root)
+	rootItem := walkItem{
+		dir:          root,
+		info:         fileInfoToDirEntry(filepath.Dir(root), fi),
+		callbackDone: !rootMatch,
+	}
 
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(1)
-		go w.doWork(&wg)
+	if conf.Ordered {
+		if opts.checkpoint != "" {
+			return ErrCheckpointOrdered
+		}
+		// All callbacks are made by this goroutine.
+		if w.newFn != nil {
+			w.fn = w.newFn()
+		}
+		return w.walkOrdered(rootItem, numWorkers)
 	}
 
-	root = cleanRootPath(root)
-	// NOTE: in BenchmarkFastWalk the size of todo averages around
-	// 170 and can be in the ~250 range at max.
-	todo := []walkItem{{dir: root, info: fileInfoToDirEntry(filepath.Dir(root), fi)}}
-	out := 0
-	for {
-		workc := w.workc
-		var workItem walkItem
-		if len(todo) == 0 {
-			workc = nil
-		} else {
-			workItem = todo[len(todo)-1]
+	w.sched = newScheduler(numWorkers, conf.Strategy)
+	if conf.MaxQueuedDirs > 0 {
+		spill, err := newSpillQueue(w.statLimit)
+		if err != nil {
+			return err
+		}
+		defer spill.Close()
+		w.sched.spill = spill
+		w.sched.maxQueued = int64(conf.MaxQueuedDirs)
+	}
+	if opts.checkpoint != "" {
+		w.sched.ckpt = new(sync.RWMutex)
+		if err := w.resume(opts.checkpoint, rootItem); err != nil {
+			return err
 		}
-		select {
-		case workc <- workItem:
-			todo = todo[:len(todo)-1]
-			out++
-		case it := <-w.enqueuec:
-			// TODO: consider appending to todo directly and using a
-			// mutext this might help with contention around select
-			todo = append(todo, it)
-		case err := <-w.resc:
-			out--
-			if err != nil {
+	} else {
+		w.sched.push(0, rootItem)
+	}
+	if conf.AutoTune {
+		w.tuner = newAutoTuner(w.sched)
+		go w.tuner.run()
+		defer w.tuner.stop()
+	}
+
+	// Make sure to wait for all workers to finish, otherwise
+	// walkFn could still be called after returning.
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go w.doWork(&wg, i)
+	}
+	if opts.checkpoint != "" {
+		c := w.startCheckpoints(opts.checkpoint)
+		wg.Wait()
+		c.stop()
+		if w.sched.err == nil {
+			if err := os.Remove(opts.checkpoint); err != nil && !os.IsNotExist(err) {
 				return err
 			}
-			if out == 0 && len(todo) == 0 {
-				// It's safe to quit here, as long as the buffered
-				// enqueue channel isn't also readable, which might
-				// happen if the worker sends both another unit of
-				// work and its result before the other select was
-				// scheduled and both w.resc and w.enqueuec were
-				// readable.
-				select {
-				case it := <-w.enqueuec:
-					todo = append(todo, it)
-				default:
-					return nil
-				}
-			}
 		}
+		return w.sched.err
 	}
+	wg.Wait()
+	return w.sched.err
 }
 
-// doWork reads directories as instructed (via workc) and runs the
-// user's callback function.
-func (w *walker) doWork(wg *sync.WaitGroup) {
+// doWork reads directories as instructed by the scheduler and runs the
+// user's callback function. Each worker has its own copy of the walker
+// since directories found by the worker are pushed to its own queue.
+func (w *walker) doWork(wg *sync.WaitGroup, worker int) {
 	defer wg.Done()
+	ww := *w
+	ww.worker = worker
+	if w.newFn != nil {
+		ww.fn = w.newFn()
+	}
+	w = &ww
 	for {
-		select {
-		case <-w.donec:
+		it, ok := w.sched.next(worker)
+		if !ok {
 			return
-		case it := <-w.workc:
-			select {
-			case <-w.donec:
-				return
-			case w.resc <- w.walk(it.dir, it.info, !it.callbackDone):
-			}
 		}
+		w.sched.finish(w.walk(it))
 	}
 }
 
 type walker struct {
-	fn fs.WalkDirFunc
-
-	donec    chan struct{} // closed on fastWalk's return
-	workc    chan walkItem // to workers
-	enqueuec chan walkItem // from workers
-	resc     chan error    // from workers
-
-	ignoredDirs []fs.FileInfo
-	maxDepth    int
-	follow      bool
-	toSlash     bool
-	sortMode    SortMode
+	fn    fs.WalkDirFunc
+	newFn func() fs.WalkDirFunc // creates a per-worker fn (optional)
+
+	sched  *scheduler
+	worker int // index of the worker using this walker (see doWork)
+
+	root         string       // root of the walk
+	match        *pathMatcher // Config.Match
+	prune        *pathMatcher // Config.Prune
+	ignoredDirs  []fs.FileInfo
+	maxDepth     int
+	maxLinkDepth int
+	maxFollowed  int64
+	followed     *atomic.Int64 // number of symlinks traversed (shared)
+	follow       bool
+	toSlash      bool
+	sortMode     SortMode
+
+	order   *orderedWalk // Config.Ordered
+	listing *[]DirEntry  // collect entries instead of visiting them (see orderedWalk)
+
+	cur   DirEntry   // directory being read (see direntVisitor.dispatch)
+	tuner *autoTuner // Config.AutoTune
+
+	dirLimit  *rateLimiter // Config.MaxDirsPerSecond
+	statLimit *rateLimiter // Config.MaxStatsPerSecond
+	throttler Throttler    // Config.Throttle
+	handle    *Walker      // see Start
 }
 
 type walkItem struct {
 	dir          string
 	info         DirEntry
-	callbackDone bool // callback already called; don't do it again
+	linkDepth    int  // number of symlinks traversed to reach dir
+	callbackDone bool // callback already called (or not needed); don't do it again
+
+	batch *direntBatch // entries of dir to visit (see direntVisitor.dispatch)
 }
 
-func (w *walker) enqueue(it walkItem) {
-	select {
-	case w.enqueuec <- it:
-	case <-w.donec:
+func (w *walker) enqueue(it walkItem) error {
+	if w.order != nil {
+		return w.order.walkChild(it)
 	}
+	w.sched.push(w.worker, it)
+	return nil
 }
 
 func (w *walker) shouldSkipDir(fi fs.FileInfo) bool {
@@ -579,6 +841,7 @@ func (w *walker) shouldTraverse(path string, de DirEntry) bool {
 		if parent == path {
 			return true
 		}
+		w.statLimit.wait()
 		parentInfo, err := os.Stat(parent)
 		if err != nil {
 			return false
@@ -608,22 +871,54 @@ func (w *walker) joinPaths(dir, base string) string {
 	return dir + string(os.PathSeparator) + base
 }
 
-func (w *walker) onDirEnt(dirName, baseName string, de DirEntry) error {
+// traverseLink enqueues the symlink at path for traversal unless doing so
+// would exceed the MaxSymlinkDepth or MaxFollowedLinks limits, in which case
+// the error is reported to the user's callback.
+func (w *walker) traverseLink(path string, de DirEntry, linkDepth int) error {
+	var err error
+	if w.maxLinkDepth > 0 && linkDepth > w.maxLinkDepth {
+		err = ErrMaxSymlinkDepth
+	} else if w.maxFollowed > 0 && w.followed.Add(1) > w.maxFollowed {
+		err = ErrMaxFollowedLinks
+	}
+	if err != nil {
+		// Second call, to report that the link was not traversed.
+		err = w.fn(path, de, &os.PathError{Op: "follow", Path: path, Err: err})
+		if err == filepath.SkipDir {
+			return nil
+		}
+		return err
+	}
+	// Set callbackDone so we don't call it twice for both the
+	// symlink-as-symlink and the symlink-as-directory later:
+	return w.enqueue(walkItem{dir: path, info: de, linkDepth: linkDepth, callbackDone: true})
+}
+
+func (w *walker) onDirEnt(dirName, baseName string, de DirEntry, linkDepth int) error {
+	if w.listing != nil {
+		*w.listing = append(*w.listing, de)
+		return nil
+	}
 	joined := w.joinPaths(dirName, baseName)
+	match, prune := w.matchPath(joined)
+	if prune {
+		return nil
+	}
 	typ := de.Type()
 	if typ == os.ModeDir {
-		w.enqueue(walkItem{dir: joined, info: de})
-		return nil
+		// Directories that do not match are traversed, but we
+		// don't call the callback for them.
+		return w.enqueue(walkItem{dir: joined, info: de, linkDepth: linkDepth, callbackDone: !match})
 	}
 
-	err := w.fn(joined, de, nil)
+	var err error
+	if match {
+		err = w.fn(joined, de, nil)
+	}
 	if typ == os.ModeSymlink {
 		if err == ErrTraverseLink {
 			if !w.follow {
-				// Set callbackDone so we don't call it twice for both the
-				// symlink-as-symlink and the symlink-as-directory later:
-				w.enqueue(walkItem{dir: joined, info: de, callbackDone: true})
-				return nil
+				return w.traverseLink(joined, de, linkDepth+1)
 			}
 			err = nil // Ignore ErrTraverseLink when Follow is true.
 		}
@@ -633,15 +928,18 @@ func (w *walker) onDirEnt(dirName, baseName string, de DirEntry) error {
 		}
 		if err == nil && w.follow && w.shouldTraverse(joined, de) {
 			// Traverse symlink
-			w.enqueue(walkItem{dir: joined, info: de, callbackDone: true})
+			return w.traverseLink(joined, de, linkDepth+1)
 		}
 	}
 	return err
 }
 
-func (w *walker) walk(root string, info DirEntry, runUserCallback bool) error {
-	if runUserCallback {
-		err := w.fn(root, info, nil)
+func (w *walker) walk(it walkItem) error {
+	if it.batch != nil {
+		return w.walkBatch(it)
+	}
+	if !it.callbackDone {
+		err := w.fn(it.dir, it.info, nil)
 		if err == filepath.SkipDir {
 			return nil
 		}
@@ -650,14 +948,35 @@ func (w *walker) walk(root string, info DirEntry, runUserCallback bool) error {
 		}
 	}
 
-	depth := info.Depth()
+	depth := it.info.Depth()
 	if w.maxDepth > 0 && depth >= w.maxDepth {
 		return nil
 	}
-	err := w.readDir(root, depth+1)
+	var err error
+	if w.order != nil {
+		err = w.order.readDir(it.dir, depth+1, it.linkDepth)
+	} else if err = w.throttle(it.dir); err == nil {
+		w.cur = it.info
+		if w.tuner != nil {
+			start := time.Now()
+			err = w.readDir(it.dir, depth+1, it.linkDepth)
+			w.tuner.record(time.Since(start))
+		} else {
+			err = w.readDir(it.dir, depth+1, it.linkDepth)
+		}
+	}
 	if err != nil {
+		if _, ok := err.(*PanicError); ok {
+			return err // Always stop the walk if the callback panicked.
+		}
+		if err == errOrderedAbort {
+			return err // Error already reported by a sub-directory.
+		}
+		if err == errDirAborted {
+			return nil // Error already handled by another worker.
+		}
 		// Second call, to report ReadDir error.
-		return w.fn(root, info, err)
+		return w.fn(it.dir, it.info, err)
 	}
 	return nil
 }
@@ -8,7 +8,7 @@ import (
 	"unsafe"
 )
 
-func (w *walker) readDir(dirName string, depth int) (err error) {
+func (w *walker) readDir(dirName string, depth, linkDepth int) (err error) {
 	var fd uintptr
 	for {
 		fd, err = opendir(dirName)
@@ -27,7 +27,7 @@ func (w *walker) readDir(dirName string, depth int) (err error) {
 	}
 	defer putDirentSlice(p)
 
-	skipFiles := false
+	v := w.newDirentVisitor(dirName, linkDepth)
 	var dirent syscall.Dirent
 	var entptr *syscall.Dirent
 	for {
@@ -53,7 +53,7 @@ func (w *walker) readDir(dirName string, depth int) (err error) {
 			continue
 		}
 		typ := dtToType(dirent.Type)
-		if skipFiles && typ.IsRegular() {
+		if v.skipping() && typ.IsRegular() {
 			continue
 		}
 		name := (*[len(syscall.Dirent{}.Name)]byte)(unsafe.Pointer(&dirent.Name))[:]
@@ -67,38 +67,28 @@ func (w *walker) readDir(dirName string, depth int) (err error) {
 		if string(name) == "." || string(name) == ".." {
 			continue
 		}
-		nm := string(name)
-		de := newUnixDirent(dirName, nm, typ, depth)
+		de := newUnixDirent(dirName, string(name), typ, depth)
+		de.limit = w.statLimit
 		if w.sortMode == SortNone {
-			if err := w.onDirEnt(dirName, nm, de); err != nil {
-				if err != ErrSkipFiles {
-					return err
-				}
-				skipFiles = true
+			if err := v.visit(de); err != nil {
+				return err
 			}
 		} else {
 			*p = append(*p, de)
+			if len(*p) == sortChunkSize && v.chunked() {
+				if err := v.visitSorted(w.sortMode, *p); err != nil {
+					return err
+				}
+				*p = (*p)[:0]
+			}
 		}
 	}
-	if w.sortMode == SortNone {
-		return nil
-	}
-
-	dents := *p
-	sortDirents(w.sortMode, dents)
-	for _, d := range dents {
-		d := d
-		if skipFiles && d.typ.IsRegular() {
-			continue
-		}
-		if err := w.onDirEnt(dirName, d.Name(), d); err != nil {
-			if err != ErrSkipFiles {
-				return err
-			}
-			skipFiles = true
+	if w.sortMode != SortNone {
+		if err := v.visitSorted(w.sortMode, *p); err != nil {
+			return err
 		}
 	}
-	return nil
+	return v.finish()
 }
 
 func dtToType(typ uint8) os.FileMode {
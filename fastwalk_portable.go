@@ -2,22 +2,25 @@
 
 package fastwalk
 
-import "os"
+import (
+	"io"
+	"io/fs"
+	"os"
+)
+
+// readDirBatchSize is the number of entries read at a time.
+const readDirBatchSize = 1024
 
 // readDir calls fn for each directory entry in dirName.
 // It does not descend into directories or follow symlinks.
 // If fn returns a non-nil error, readDir returns with that error
 // immediately.
-func (w *walker) readDir(dirName string, depth int) error {
+func (w *walker) readDir(dirName string, depth, linkDepth int) error {
 	f, err := os.Open(dirName)
 	if err != nil {
 		return err
 	}
-	des, readErr := f.ReadDir(-1)
-	f.Close()
-	if readErr != nil && len(des) == 0 {
-		return readErr
-	}
+	defer f.Close()
 
 	var p *[]DirEntry
 	if w.sortMode != SortNone {
@@ -25,41 +28,53 @@ func (w *walker) readDir(dirName string, depth int) error {
 	}
 	defer putDirentSlice(p)
 
-	var skipFiles bool
-	for _, d := range des {
-		if skipFiles && d.Type().IsRegular() {
-			continue
+	// Read the directory in batches so that the entries of large
+	// directories are visited while the directory is being read.
+	v := w.newDirentVisitor(dirName, linkDepth)
+	var readErr error
+	nread := 0
+	for readErr == nil {
+		var des []fs.DirEntry
+		des, readErr = f.ReadDir(readDirBatchSize)
+		if readErr == io.EOF {
+			readErr = nil
+			break
 		}
-		// Need to use FileMode.Type().Type() for fs.DirEntry
-		e := newDirEntry(dirName, d, depth)
-		if w.sortMode == SortNone {
-			if err := w.onDirEnt(dirName, d.Name(), e); err != nil {
-				if err != ErrSkipFiles {
+		if readErr != nil && nread+len(des) == 0 {
+			return readErr
+		}
+		nread += len(des)
+		for _, d := range des {
+			if v.skipping() && d.Type().IsRegular() {
+				continue
+			}
+			// Need to use FileMode.Type().Type() for fs.DirEntry
+			e := newDirEntry(dirName, d, depth)
+			if w.statLimit != nil {
+				e.(*portableDirent).limit = w.statLimit
+			}
+			if w.sortMode == SortNone {
+				if err := v.visit(e); err != nil {
 					return err
 				}
-				skipFiles = true
+			} else {
+				*p = append(*p, e)
+				if len(*p) == sortChunkSize && v.chunked() {
+					if err := v.visitSorted(w.sortMode, *p); err != nil {
+						return err
+					}
+					*p = (*p)[:0]
+				}
 			}
-		} else {
-			*p = append(*p, e)
 		}
 	}
-	if w.sortMode == SortNone {
-		return readErr
-	}
-
-	dents := *p
-	sortDirents(w.sortMode, dents)
-	for _, d := range dents {
-		d := d
-		if skipFiles && d.Type().IsRegular() {
-			continue
-		}
-		if err := w.onDirEnt(dirName, d.Name(), d); err != nil {
-			if err != ErrSkipFiles {
-				return err
-			}
-			skipFiles = true
+	if w.sortMode != SortNone {
+		if err := v.visitSorted(w.sortMode, *p); err != nil {
+			return err
 		}
 	}
+	if err := v.finish(); err != nil {
+		return err
+	}
 	return readErr
 }
@@ -23,6 +23,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/charlievieth/fastwalk"
 )
@@ -956,6 +957,174 @@ func TestFastWalk_Follow_ErrTraverseLink(t *testing.T) {
 		})
 }
 
+func TestFastWalk_MaxSymlinkDepth(t *testing.T) {
+	tempdir := t.TempDir()
+	testCreateFiles(t, tempdir, map[string]string{
+		"d1/link2": "LINK:../d2",
+		"d2/link3": "LINK:../d3",
+		"d3/f.txt": "one",
+		"link1":    "LINK:d1",
+	})
+
+	test := func(t *testing.T, conf *fastwalk.Config, fn fs.WalkDirFunc) {
+		var mu sync.Mutex
+		got := make(map[string]os.FileMode)
+		var limited []string
+		err := fastwalk.Walk(conf, tempdir, func(path string, de fs.DirEntry, err error) error {
+			key := filepath.ToSlash(strings.TrimPrefix(path, tempdir))
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if !errors.Is(err, fastwalk.ErrMaxSymlinkDepth) {
+					t.Errorf("%s: unexpected error: %v", key, err)
+				}
+				limited = append(limited, key)
+				return nil
+			}
+			got[key] = de.Type()
+			return fn(path, de, err)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]os.FileMode{
+			"":                          os.ModeDir,
+			"/src":                      os.ModeDir,
+			"/src/d1":                   os.ModeDir,
+			"/src/d1/link2":             os.ModeSymlink,
+			"/src/d1/link2/link3":       os.ModeSymlink,
+			"/src/d1/link2/link3/f.txt": 0,
+			"/src/d2":                   os.ModeDir,
+			"/src/d2/link3":             os.ModeSymlink,
+			"/src/d2/link3/f.txt":       0,
+			"/src/d3":                   os.ModeDir,
+			"/src/d3/f.txt":             0,
+			"/src/link1":                os.ModeSymlink,
+			"/src/link1/link2":          os.ModeSymlink,
+			"/src/link1/link2/link3":    os.ModeSymlink,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("walk mismatch.\n got:\n%v\nwant:\n%v", formatFileModes(got), formatFileModes(want))
+			diffFileModes(t, got, want)
+		}
+		if want := []string{"/src/link1/link2/link3"}; !reflect.DeepEqual(limited, want) {
+			t.Errorf("limited links = %q; want: %q", limited, want)
+		}
+	}
+
+	t.Run("Follow", func(t *testing.T) {
+		conf := fastwalk.Config{Follow: true, MaxSymlinkDepth: 2}
+		test(t, &conf, func(path string, de fs.DirEntry, err error) error {
+			return nil
+		})
+	})
+
+	t.Run("ErrTraverseLink", func(t *testing.T) {
+		conf := fastwalk.Config{MaxSymlinkDepth: 2}
+		test(t, &conf, func(path string, de fs.DirEntry, err error) error {
+			if de.Type()&os.ModeSymlink != 0 {
+				return fastwalk.ErrTraverseLink
+			}
+			return nil
+		})
+	})
+}
+
+func TestFastWalk_MaxFollowedLinks(t *testing.T) {
+	tempdir := t.TempDir()
+	files := map[string]string{
+		"foo/foo.go": "one",
+	}
+	for i := 0; i < 8; i++ {
+		files[fmt.Sprintf("link%d", i)] = "LINK:foo"
+	}
+	testCreateFiles(t, tempdir, files)
+
+	const maxLinks = 3
+	conf := fastwalk.Config{
+		Follow:           true,
+		MaxFollowedLinks: maxLinks,
+	}
+	var followed, limited atomic.Int32
+	err := fastwalk.Walk(&conf, tempdir, func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			if !errors.Is(err, fastwalk.ErrMaxFollowedLinks) {
+				t.Errorf("%s: unexpected error: %v", path, err)
+			}
+			limited.Add(1)
+			return nil
+		}
+		if de.Name() == "foo.go" && filepath.Base(filepath.Dir(path)) != "foo" {
+			followed.Add(1)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := followed.Load(); n != maxLinks {
+		t.Errorf("followed %d links; want: %d", n, maxLinks)
+	}
+	if n := limited.Load(); n != 8-maxLinks {
+		t.Errorf("limited %d links; want: %d", n, 8-maxLinks)
+	}
+}
+
+func TestFastWalk_MatchPrune(t *testing.T) {
+	files := map[string]string{
+		"foo/foo.go":          "one",
+		"foo/foo.txt":         "two",
+		"bar/bar.go":          "three",
+		"node_modules/a.go":   "four",
+		"bar/node_modules/b":  "five",
+		"bar/node_modules.go": "six",
+	}
+	fn := func(path string, de fs.DirEntry, err error) error {
+		requireNoError(t, err)
+		return nil
+	}
+
+	t.Run("Match", func(t *testing.T) {
+		conf := fastwalk.Config{
+			Match: regexp.MustCompile(`^src/.*\.go$`),
+		}
+		testFastWalkConf(t, &conf, files, fn, map[string]os.FileMode{
+			"/src/bar/bar.go":          0,
+			"/src/bar/node_modules.go": 0,
+			"/src/foo/foo.go":          0,
+			"/src/node_modules/a.go":   0,
+		})
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		conf := fastwalk.Config{
+			Prune: regexp.MustCompile(`(^|/)node_modules$`),
+		}
+		testFastWalkConf(t, &conf, files, fn, map[string]os.FileMode{
+			"":                         os.ModeDir,
+			"/src":                     os.ModeDir,
+			"/src/bar":                 os.ModeDir,
+			"/src/bar/bar.go":          0,
+			"/src/bar/node_modules.go": 0,
+			"/src/foo":                 os.ModeDir,
+			"/src/foo/foo.go":          0,
+			"/src/foo/foo.txt":         0,
+		})
+	})
+
+	t.Run("MatchPrune", func(t *testing.T) {
+		conf := fastwalk.Config{
+			Match: regexp.MustCompile(`\.go$`),
+			Prune: regexp.MustCompile(`(^|/)node_modules$`),
+		}
+		testFastWalkConf(t, &conf, files, fn, map[string]os.FileMode{
+			"/src/bar/bar.go":          0,
+			"/src/bar/node_modules.go": 0,
+			"/src/foo/foo.go":          0,
+		})
+	})
+}
+
 func TestFastWalk_Error(t *testing.T) {
 	tmp := t.TempDir()
 	for _, child := range []string{
@@ -978,6 +1147,48 @@ func TestFastWalk_Error(t *testing.T) {
 	}
 }
 
+func TestFastWalk_RecoverPanics(t *testing.T) {
+	tmp := t.TempDir()
+	for _, child := range []string{
+		"foo/foo.go",
+		"bar/bar.go",
+		"bar/panic.go",
+	} {
+		if err := writeFile(filepath.Join(tmp, child), child, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conf := fastwalk.Config{RecoverPanics: true}
+	errPanic := errors.New("panic value")
+	var calls atomic.Int32
+	err := fastwalk.Walk(&conf, tmp, func(path string, _ fs.DirEntry, err error) error {
+		calls.Add(1)
+		if filepath.Base(path) == "panic.go" {
+			panic(errPanic)
+		}
+		// Ignore all errors to make sure that the panic
+		// still stops the walk.
+		return nil
+	})
+	var perr *fastwalk.PanicError
+	if !errors.As(err, &perr) {
+		t.Fatalf("want error of type %T got: %#v", perr, err)
+	}
+	if want := filepath.Join(tmp, "bar", "panic.go"); perr.Path != want {
+		t.Errorf("PanicError.Path = %q; want: %q", perr.Path, want)
+	}
+	if perr.Value != errPanic {
+		t.Errorf("PanicError.Value = %v; want: %v", perr.Value, errPanic)
+	}
+	if !errors.Is(err, errPanic) {
+		t.Error("PanicError should wrap the panic value when it is an error")
+	}
+	if !bytes.Contains(perr.Stack, []byte("TestFastWalk_RecoverPanics")) {
+		t.Errorf("PanicError.Stack does not contain the test function:\n%s", perr.Stack)
+	}
+}
+
 func TestFastWalk_ErrNotExist(t *testing.T) {
 	tmp := t.TempDir()
 	if err := os.Remove(tmp); err != nil {
@@ -1168,6 +1379,375 @@ func TestFastWalk_SortMode(t *testing.T) {
 	}
 }
 
+func TestFastWalk_Ordered(t *testing.T) {
+	tempdir := t.TempDir()
+	for i := 0; i < 200; i++ {
+		name := filepath.Join(tempdir, strconv.Itoa(i%7), strconv.Itoa(i%13), strconv.Itoa(i%3), "f"+strconv.Itoa(i))
+		if err := writeFile(name, "data", 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var want []string
+	err := filepath.WalkDir(tempdir, func(path string, _ fs.DirEntry, err error) error {
+		want = append(want, path)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, numWorkers := range []int{1, 4, 16} {
+		t.Run(strconv.Itoa(numWorkers), func(t *testing.T) {
+			conf := fastwalk.Config{Ordered: true, NumWorkers: numWorkers}
+			var got []string
+			var active atomic.Int32
+			err := fastwalk.Walk(&conf, tempdir, func(path string, _ fs.DirEntry, err error) error {
+				if active.Add(1) != 1 {
+					t.Error("concurrent callback")
+				}
+				defer active.Add(-1)
+				got = append(got, path)
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("order:\ngot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestFastWalk_OrderedSkip(t *testing.T) {
+	tempdir := t.TempDir()
+	for _, name := range []string{
+		"a/a1.txt", "a/skipdir/b.txt", "a/z.txt",
+		"b/b1.txt", "b/skipfile.txt", "b/z.txt", "b/zz/c.txt",
+		"c/c1.txt", "c/skipfiles/d1.txt", "c/skipfiles/d2.txt", "c/skipfiles/sub/d3.txt",
+		"d/d/d/d.txt",
+	} {
+		if err := writeFile(filepath.Join(tempdir, name), "data", 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conf := fastwalk.Config{Ordered: true, MaxDepth: 3}
+	var got []string
+	err := fastwalk.Walk(&conf, tempdir, func(path string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(tempdir, path)
+		if err != nil {
+			return err
+		}
+		got = append(got, filepath.ToSlash(rel))
+		switch filepath.Base(path) {
+		case "skipdir", "skipfile.txt":
+			return filepath.SkipDir
+		case "d1.txt":
+			return fastwalk.ErrSkipFiles
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		".",
+		"a", "a/a1.txt", "a/skipdir", "a/z.txt",
+		"b", "b/b1.txt", "b/skipfile.txt",
+		"c", "c/c1.txt", "c/skipfiles", "c/skipfiles/d1.txt", "c/skipfiles/sub",
+		"d", "d/d", "d/d/d",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:  %q\nwant: %q", got, want)
+	}
+
+	errStop := errors.New("stop")
+	var n int
+	err = fastwalk.Walk(&conf, tempdir, func(path string, _ fs.DirEntry, err error) error {
+		n++
+		if filepath.Base(path) == "b1.txt" {
+			return errStop
+		}
+		return err
+	})
+	if err != errStop {
+		t.Errorf("got error: %v want: %v", err, errStop)
+	}
+	// 8 entries plus the second call for directory "b" with errStop.
+	if want := 9; n != want {
+		t.Errorf("callback called %d times want: %d", n, want)
+	}
+}
+
+func TestFastWalk_MaxQueuedDirs(t *testing.T) {
+	files := map[string]string{}
+	want := map[string]os.FileMode{
+		"":     os.ModeDir,
+		"/src": os.ModeDir,
+	}
+	for i := 0; i < 200; i++ {
+		dir := "d" + strconv.Itoa(i)
+		files[dir+"/sub/foo.go"] = "package foo"
+		want["/src/"+dir] = os.ModeDir
+		want["/src/"+dir+"/sub"] = os.ModeDir
+		want["/src/"+dir+"/sub/foo.go"] = 0
+	}
+	files["link"] = "LINK:d0"
+	want["/src/link"] = os.ModeSymlink
+	want["/src/link/sub"] = os.ModeDir
+	want["/src/link/sub/foo.go"] = 0
+
+	for _, strategy := range []fastwalk.Strategy{fastwalk.DepthFirst, fastwalk.BreadthFirst} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			conf := fastwalk.Config{
+				Follow:        true,
+				NumWorkers:    4,
+				Strategy:      strategy,
+				MaxQueuedDirs: 8,
+			}
+			testFastWalkConf(t, &conf, files,
+				func(path string, de fs.DirEntry, err error) error {
+					requireNoError(t, err)
+					// Make sure that directories read back from
+					// the spill file can be stat'd.
+					if de.IsDir() {
+						fi, err := de.Info()
+						if err != nil {
+							t.Error(err)
+						} else if !fi.IsDir() {
+							t.Errorf("%s: Info().IsDir() = false", path)
+						}
+					}
+					if i := strings.LastIndex(filepath.ToSlash(path), "/src"); i != -1 {
+						want := strings.Count(filepath.ToSlash(path[i:]), "/")
+						if got := fastwalk.DirEntryDepth(de); got != want {
+							t.Errorf("%s: Depth() = %d; want: %d", path, got, want)
+						}
+					}
+					return nil
+				},
+				want)
+		})
+	}
+}
+
+func TestFastWalk_AutoTune(t *testing.T) {
+	files := map[string]string{}
+	want := map[string]os.FileMode{
+		"":     os.ModeDir,
+		"/src": os.ModeDir,
+	}
+	for i := 0; i < 50; i++ {
+		dir := "d" + strconv.Itoa(i)
+		for j := 0; j < 4; j++ {
+			sub := dir + "/s" + strconv.Itoa(j)
+			files[sub+"/foo.go"] = "package foo"
+			want["/src/"+sub] = os.ModeDir
+			want["/src/"+sub+"/foo.go"] = 0
+		}
+		want["/src/"+dir] = os.ModeDir
+	}
+	conf := fastwalk.Config{
+		NumWorkers: 8,
+		AutoTune:   true,
+	}
+	testFastWalkConf(t, &conf, files,
+		func(path string, de fs.DirEntry, err error) error {
+			requireNoError(t, err)
+			return nil
+		},
+		want)
+}
+
+type countThrottler struct {
+	mu   sync.Mutex
+	dirs []string
+}
+
+func (c *countThrottler) Throttle(dir string) {
+	c.mu.Lock()
+	c.dirs = append(c.dirs, dir)
+	c.mu.Unlock()
+}
+
+func TestFastWalk_Throttle(t *testing.T) {
+	files := map[string]string{}
+	for i := 0; i < 40; i++ {
+		files["d"+strconv.Itoa(i)+"/foo.go"] = "package foo"
+	}
+	tempdir := t.TempDir()
+	testCreateFiles(t, tempdir, files)
+	root := filepath.Join(tempdir, "src")
+
+	for _, ordered := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Ordered=%t", ordered), func(t *testing.T) {
+			th := new(countThrottler)
+			conf := fastwalk.Config{
+				NumWorkers:        4,
+				Ordered:           ordered,
+				MaxDirsPerSecond:  100,
+				MaxStatsPerSecond: 100,
+				Throttle:          th,
+			}
+			err := fastwalk.Walk(&conf, root, func(path string, de fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !de.IsDir() {
+					if _, err := de.Info(); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The root and its 40 sub-directories.
+			if len(th.dirs) != 41 {
+				t.Errorf("Throttle called %d times; want: 41", len(th.dirs))
+			}
+		})
+	}
+}
+
+func TestFastWalk_MaxStatsPerSecond(t *testing.T) {
+	files := map[string]string{}
+	for i := 0; i < 60; i++ {
+		files["f"+strconv.Itoa(i)] = "x"
+	}
+	tempdir := t.TempDir()
+	testCreateFiles(t, tempdir, files)
+
+	// 60 stats at 40/s with a burst of 40: the last 20 take ~500ms.
+	conf := fastwalk.Config{NumWorkers: 4, MaxStatsPerSecond: 40}
+	start := time.Now()
+	err := fastwalk.Walk(&conf, tempdir, func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if de.Type().IsRegular() {
+			if _, err := de.Info(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 400*time.Millisecond {
+		t.Errorf("walk took %s; want: ≥ 500ms", d)
+	}
+}
+
+func TestStart(t *testing.T) {
+	files := map[string]string{}
+	for i := 0; i < 20; i++ {
+		files["d"+strconv.Itoa(i)+"/foo.go"] = "package foo"
+	}
+	tempdir := t.TempDir()
+	testCreateFiles(t, tempdir, files)
+	root := filepath.Join(tempdir, "src")
+	const total = 1 + 20*2 // root + dirs + files
+
+	t.Run("Wait", func(t *testing.T) {
+		var n atomic.Int64
+		wk := fastwalk.Start(&fastwalk.Config{NumWorkers: 4}, root, func(_ string, _ fs.DirEntry, err error) error {
+			n.Add(1)
+			return err
+		})
+		if err := wk.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		if n.Load() != total {
+			t.Errorf("visited %d paths; want: %d", n.Load(), total)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		wk := fastwalk.Start(nil, filepath.Join(tempdir, "missing"), func(_ string, _ fs.DirEntry, err error) error {
+			return err
+		})
+		if err := wk.Wait(); !os.IsNotExist(err) {
+			t.Errorf("Wait() = %v; want: %v", err, fs.ErrNotExist)
+		}
+	})
+
+	t.Run("PauseResume", func(t *testing.T) {
+		var n atomic.Int64
+		var wk *fastwalk.Walker
+		paused := make(chan struct{})
+		wk = fastwalk.Start(&fastwalk.Config{NumWorkers: 4}, root, func(path string, _ fs.DirEntry, err error) error {
+			if n.Add(1) == 1 {
+				<-paused // wait for wk to be assigned
+				// Pausing from the root's callback blocks before the
+				// root is read.
+				wk.Pause()
+			}
+			return err
+		})
+		close(paused)
+		time.Sleep(50 * time.Millisecond)
+		if got := n.Load(); got != 1 {
+			t.Fatalf("visited %d paths while paused; want: 1", got)
+		}
+		wk.Resume()
+		if err := wk.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		if n.Load() != total {
+			t.Errorf("visited %d paths; want: %d", n.Load(), total)
+		}
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		var n atomic.Int64
+		var wk *fastwalk.Walker
+		started := make(chan struct{})
+		wk = fastwalk.Start(&fastwalk.Config{NumWorkers: 4}, root, func(path string, _ fs.DirEntry, err error) error {
+			if n.Add(1) == 1 {
+				<-started
+				wk.Pause()
+			}
+			return err
+		})
+		close(started)
+		time.Sleep(10 * time.Millisecond)
+		// Stopping a paused walk must not block.
+		wk.Stop()
+		if err := wk.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		if got := n.Load(); got != 1 {
+			t.Errorf("visited %d paths; want: 1", got)
+		}
+	})
+}
+
+func TestStrategyString(t *testing.T) {
+	tests := []struct {
+		strategy fastwalk.Strategy
+		want     string
+	}{
+		{fastwalk.DepthFirst, "DepthFirst"},
+		{fastwalk.BreadthFirst, "BreadthFirst"},
+		{fastwalk.Hybrid, "Hybrid"},
+		{100, "Strategy(100)"},
+	}
+	for _, test := range tests {
+		got := test.strategy.String()
+		if got != test.want {
+			t.Errorf("%d: got: %s want: %s", test.strategy, got, test.want)
+		}
+	}
+}
+
 func TestSortModeString(t *testing.T) {
 	tests := []struct {
 		mode fastwalk.SortMode
@@ -1188,6 +1768,51 @@ func TestSortModeString(t *testing.T) {
 	}
 }
 
+func TestFastWalk_Strategy(t *testing.T) {
+	tempdir := t.TempDir()
+	for _, name := range []string{
+		"a/b/c/d/e.txt",
+		"f/g/h/i/j.txt",
+		"k/l/m/n/o.txt",
+	} {
+		if err := writeFile(filepath.Join(tempdir, name), "data", 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// dirDepths returns the depths of directories in the order visited.
+	dirDepths := func(t *testing.T, strategy fastwalk.Strategy) []int {
+		// Use one worker so that the order is deterministic.
+		conf := fastwalk.Config{NumWorkers: 1, Strategy: strategy}
+		var depths []int
+		err := fastwalk.Walk(&conf, tempdir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && d.IsDir() {
+				depths = append(depths, fastwalk.DirEntryDepth(d))
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return depths
+	}
+
+	t.Run("DepthFirst", func(t *testing.T) {
+		want := []int{0, 1, 2, 3, 4, 1, 2, 3, 4, 1, 2, 3, 4}
+		if got := dirDepths(t, fastwalk.DepthFirst); !reflect.DeepEqual(got, want) {
+			t.Errorf("got: %v want: %v", got, want)
+		}
+	})
+	for _, strategy := range []fastwalk.Strategy{fastwalk.BreadthFirst, fastwalk.Hybrid} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			want := []int{0, 1, 1, 1, 2, 2, 2, 3, 3, 3, 4, 4, 4}
+			if got := dirDepths(t, strategy); !reflect.DeepEqual(got, want) {
+				t.Errorf("got: %v want: %v", got, want)
+			}
+		})
+	}
+}
+
 func TestFastWalk_Depth(t *testing.T) {
 	tmp := filepath.Join(t.TempDir(), "root0")
 	for _, r := range "abcdef" {
@@ -1541,6 +2166,10 @@ func BenchmarkFastWalkSort(b *testing.B) {
 	}
 }
 
+func BenchmarkFastWalkOrdered(b *testing.B) {
+	benchmarkFastWalk(b, &fastwalk.Config{Ordered: true}, nil)
+}
+
 func BenchmarkFastWalkFollow(b *testing.B) {
 	benchmarkFastWalk(b, &fastwalk.Config{Follow: true}, nil)
 }
@@ -1570,9 +2199,21 @@ func BenchmarkFastWalkNumWorkers(b *testing.B) {
 		b.Skip("Skipping: short test")
 	}
 
+	var numWorkers []int
+	for i := 2; i <= runtime.NumCPU(); i += 2 {
+		numWorkers = append(numWorkers, i)
+	}
+	// Always include 8, 32 and 64 workers so that results can be compared
+	// between machines and to measure the scheduling overhead when there
+	// are more workers than CPUs.
+	for _, n := range []int{8, 32, 64} {
+		if n > runtime.NumCPU() {
+			numWorkers = append(numWorkers, n)
+		}
+	}
+
 	runBench := func(b *testing.B, walkFn fs.WalkDirFunc) {
-		maxWorkers := runtime.NumCPU()
-		for i := 2; i <= maxWorkers; i += 2 {
+		for _, i := range numWorkers {
 			b.Run(fmt.Sprint(i), func(b *testing.B) {
 				conf := fastwalk.Config{
 					NumWorkers: i,
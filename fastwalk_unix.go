@@ -20,7 +20,7 @@ const blockSize = 8192
 // value used to represent a syscall.DT_UNKNOWN Dirent.Type.
 const unknownFileMode os.FileMode = ^os.FileMode(0)
 
-func (w *walker) readDir(dirName string, depth int) error {
+func (w *walker) readDir(dirName string, depth, linkDepth int) error {
 	fd, err := open(dirName, 0, 0)
 	if err != nil {
 		return &os.PathError{Op: "open", Path: dirName, Err: err}
@@ -33,23 +33,23 @@ func (w *walker) readDir(dirName string, depth int) error {
 	}
 	defer putDirentSlice(p)
 
-	// The buffer must be at least a block long.
-	buf := make([]byte, blockSize) // stack-allocated; doesn't escape
-	bufp := 0                      // starting read position in buf
-	nbuf := 0                      // end valid data in buf
-	skipFiles := false
+	var db direntBuffer
+	defer db.release()
+	var buf []byte // entries read but not yet parsed
+	bufp := 0      // starting read position in buf
+	v := w.newDirentVisitor(dirName, linkDepth)
 	for {
-		if bufp >= nbuf {
+		if bufp >= len(buf) {
 			bufp = 0
-			nbuf, err = readDirent(fd, buf)
+			buf, err = db.read(fd)
 			if err != nil {
 				return os.NewSyscallError("readdirent", err)
 			}
-			if nbuf <= 0 {
+			if len(buf) == 0 {
 				break // exit loop
 			}
 		}
-		consumed, name, typ := dirent.Parse(buf[bufp:nbuf])
+		consumed, name, typ := dirent.Parse(buf[bufp:])
 		bufp += consumed
 
 		if name == "" || name == "." || name == ".." {
@@ -59,6 +59,7 @@ func (w *walker) readDir(dirName string, depth int) error {
 		// support Dirent.Type and have DT_UNKNOWN (0) there
 		// instead.
 		if typ == unknownFileMode {
+			w.statLimit.wait()
 			fi, err := os.Lstat(dirName + "/" + name)
 			if err != nil {
 				// It got deleted in the meantime.
@@ -69,41 +70,31 @@ func (w *walker) readDir(dirName string, depth int) error {
 			}
 			typ = fi.Mode() & os.ModeType
 		}
-		if skipFiles && typ.IsRegular() {
+		if v.skipping() && typ.IsRegular() {
 			continue
 		}
 		de := newUnixDirent(dirName, name, typ, depth)
+		de.limit = w.statLimit
 		if w.sortMode == SortNone {
-			if err := w.onDirEnt(dirName, name, de); err != nil {
-				if err == ErrSkipFiles {
-					skipFiles = true
-					continue
-				}
+			if err := v.visit(de); err != nil {
 				return err
 			}
 		} else {
 			*p = append(*p, de)
+			if len(*p) == sortChunkSize && v.chunked() {
+				if err := v.visitSorted(w.sortMode, *p); err != nil {
+					return err
+				}
+				*p = (*p)[:0]
+			}
 		}
 	}
-	if w.sortMode == SortNone {
-		return nil
-	}
-
-	dents := *p
-	sortDirents(w.sortMode, dents)
-	for _, d := range dents {
-		d := d
-		if skipFiles && d.typ.IsRegular() {
-			continue
-		}
-		if err := w.onDirEnt(dirName, d.Name(), d); err != nil {
-			if err != ErrSkipFiles {
-				return err
-			}
-			skipFiles = true
+	if w.sortMode != SortNone {
+		if err := v.visitSorted(w.sortMode, *p); err != nil {
+			return err
 		}
 	}
-	return nil
+	return v.finish()
 }
 
 // According to https://golang.org/doc/go1.14#runtime
@@ -120,12 +111,3 @@ func open(path string, mode int, perm uint32) (fd int, err error) {
 		}
 	}
 }
-
-func readDirent(fd int, buf []byte) (n int, err error) {
-	for {
-		nbuf, err := syscall.ReadDirent(fd, buf)
-		if err != syscall.EINTR {
-			return nbuf, err
-		}
-	}
-}
@@ -0,0 +1,119 @@
+package fastwalk
+
+import (
+	"io/fs"
+	"time"
+)
+
+// A Filter drops entries that do not match all of its predicates before
+// they reach the [fs.WalkDirFunc]. It covers the metadata tests of find(1)
+// (-size, -mtime, -user, -group and -perm). The zero value matches all
+// entries and each predicate is disabled when its field is the zero value.
+//
+// The entry is only stat'd (via [fs.DirEntry.Info], which describes
+// symbolic links and not their targets) when a predicate requires it
+// and the result is cached by the [DirEntry]. If Info returns an error
+// it is passed to the WalkDirFunc.
+//
+// Like [MatchName], directories that do not match are still traversed,
+// but the WalkDirFunc is not called for them. Entries passed with an
+// error are always passed to the WalkDirFunc.
+//
+// Filter.Wrap is a [Middleware] and can be used with [Chain]:
+//
+//	filter := &fastwalk.Filter{MinSize: 1 << 20}
+//	err := fastwalk.Walk(conf, root, fastwalk.Chain(walkFn, filter.Wrap))
+type Filter struct {
+	// Only match entries that are at least MinSize bytes.
+	MinSize int64
+
+	// Only match entries that are at most MaxSize bytes.
+	// A value of zero or less disables this predicate.
+	MaxSize int64
+
+	// Only match entries modified after ModifiedAfter.
+	ModifiedAfter time.Time
+
+	// Only match entries modified before ModifiedBefore.
+	ModifiedBefore time.Time
+
+	// Only match entries owned by user ID *Uid. Entries never match on
+	// platforms that do not support file ownership (such as Windows).
+	Uid *uint32
+
+	// Only match entries owned by group ID *Gid. Entries never match on
+	// platforms that do not support file ownership (such as Windows).
+	Gid *uint32
+
+	// Only match entries with all of the permission bits in PermMask
+	// set (this is equivalent to "find -perm -mode"). The permission bits
+	// are fs.ModePerm, fs.ModeSetuid, fs.ModeSetgid and fs.ModeSticky,
+	// other bits are ignored.
+	PermMask fs.FileMode
+}
+
+// permBits are the mode bits compared by Filter.PermMask.
+const permBits = fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+
+// needsInfo returns if any predicate requires the entry's FileInfo.
+func (f *Filter) needsInfo() bool {
+	return f.MinSize > 0 || f.MaxSize > 0 || !f.ModifiedAfter.IsZero() ||
+		!f.ModifiedBefore.IsZero() || f.Uid != nil || f.Gid != nil ||
+		f.PermMask&permBits != 0
+}
+
+// Match returns if fi matches all of the Filter's predicates.
+func (f *Filter) Match(fi fs.FileInfo) bool {
+	if f.MinSize > 0 && fi.Size() < f.MinSize {
+		return false
+	}
+	if f.MaxSize > 0 && fi.Size() > f.MaxSize {
+		return false
+	}
+	if !f.ModifiedAfter.IsZero() || !f.ModifiedBefore.IsZero() {
+		mtime := fi.ModTime()
+		if !f.ModifiedAfter.IsZero() && !mtime.After(f.ModifiedAfter) {
+			return false
+		}
+		if !f.ModifiedBefore.IsZero() && !mtime.Before(f.ModifiedBefore) {
+			return false
+		}
+	}
+	if perm := f.PermMask & permBits; perm != 0 && fi.Mode()&perm != perm {
+		return false
+	}
+	if f.Uid != nil || f.Gid != nil {
+		uid, gid, ok := fileOwner(fi)
+		if !ok {
+			return false
+		}
+		if f.Uid != nil && uid != *f.Uid {
+			return false
+		}
+		if f.Gid != nil && gid != *f.Gid {
+			return false
+		}
+	}
+	return true
+}
+
+// Wrap wraps walkFn so that it is only called for entries that match the
+// Filter. The Filter must not be modified after Wrap is called.
+func (f *Filter) Wrap(walkFn fs.WalkDirFunc) fs.WalkDirFunc {
+	if !f.needsInfo() {
+		return walkFn
+	}
+	return func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d == nil {
+			return walkFn(path, d, err)
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return walkFn(path, d, err)
+		}
+		if !f.Match(fi) {
+			return nil
+		}
+		return walkFn(path, d, nil)
+	}
+}
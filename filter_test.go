@@ -0,0 +1,156 @@
+package fastwalk_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/charlievieth/fastwalk"
+)
+
+func TestFilter(t *testing.T) {
+	tempdir := t.TempDir()
+	files := map[string]string{
+		"empty.txt":     "",
+		"small.txt":     "small",
+		"sub/large.txt": strings.Repeat("large", 1024),
+		"sub/exec.sh":   "#!/bin/sh",
+	}
+	for name, data := range files {
+		if err := writeFile(filepath.Join(tempdir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(tempdir, "sub/exec.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(tempdir, "small.txt"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	walk := func(t *testing.T, filter *fastwalk.Filter) []string {
+		var mu sync.Mutex
+		var got []string
+		walkFn := fastwalk.Chain(func(path string, d fs.DirEntry, err error) error {
+			requireNoError(t, err)
+			if d.Type().IsRegular() {
+				rel, err := filepath.Rel(tempdir, path)
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				got = append(got, filepath.ToSlash(rel))
+				mu.Unlock()
+			}
+			return nil
+		}, filter.Wrap)
+		if err := fastwalk.Walk(nil, tempdir, walkFn); err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(got)
+		return got
+	}
+
+	uid := uint32(os.Getuid())
+	tests := []struct {
+		name   string
+		filter fastwalk.Filter
+		want   []string
+	}{
+		{
+			name:   "Zero",
+			filter: fastwalk.Filter{},
+			want:   []string{"empty.txt", "small.txt", "sub/exec.sh", "sub/large.txt"},
+		},
+		{
+			name:   "MinSize",
+			filter: fastwalk.Filter{MinSize: 1024},
+			want:   []string{"sub/large.txt"},
+		},
+		{
+			name:   "MaxSize",
+			filter: fastwalk.Filter{MinSize: 1, MaxSize: 1024},
+			want:   []string{"small.txt", "sub/exec.sh"},
+		},
+		{
+			name:   "ModifiedAfter",
+			filter: fastwalk.Filter{ModifiedAfter: time.Now().Add(-24 * time.Hour)},
+			want:   []string{"empty.txt", "sub/exec.sh", "sub/large.txt"},
+		},
+		{
+			name:   "ModifiedBefore",
+			filter: fastwalk.Filter{ModifiedBefore: time.Now().Add(-24 * time.Hour)},
+			want:   []string{"small.txt"},
+		},
+		{
+			name:   "PermMask",
+			filter: fastwalk.Filter{PermMask: 0100},
+			want:   []string{"sub/exec.sh"},
+		},
+		{
+			name:   "Uid",
+			filter: fastwalk.Filter{Uid: &uid},
+			want:   []string{"empty.txt", "small.txt", "sub/exec.sh", "sub/large.txt"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if runtime.GOOS == "windows" {
+				switch test.name {
+				case "PermMask":
+					t.Skip("permissions are not supported on Windows")
+				case "Uid":
+					test.want = nil // file ownership is not supported
+				}
+			}
+			got := walk(t, &test.filter)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got: %q want: %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFilterPermMaskSpecialBits(t *testing.T) {
+	fsys := fstest.MapFS{
+		"setuid": {Mode: fs.ModeSetuid | 0755},
+		"setgid": {Mode: fs.ModeSetgid | 0755},
+		"sticky": {Mode: fs.ModeDir | fs.ModeSticky | 0777},
+		"plain":  {Mode: 0755},
+	}
+	tests := []struct {
+		mask fs.FileMode
+		want []string
+	}{
+		{fs.ModeSetuid, []string{"setuid"}},
+		{fs.ModeSetgid | 0100, []string{"setgid"}},
+		{fs.ModeSticky, []string{"sticky"}},
+		{0755, []string{"plain", "setgid", "setuid", "sticky"}},
+		{fs.ModeDir, []string{"plain", "setgid", "setuid", "sticky"}}, // ignored
+	}
+	for _, test := range tests {
+		f := fastwalk.Filter{PermMask: test.mask}
+		var got []string
+		for _, name := range []string{"plain", "setgid", "setuid", "sticky"} {
+			fi, err := fs.Stat(fsys, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Match(fi) {
+				got = append(got, name)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("PermMask %v: got: %q want: %q", test.mask, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+//go:build darwin || aix || dragonfly || freebsd || (js && wasm) || linux || netbsd || openbsd || solaris
+
+package fastwalk
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+func fileOwner(fi fs.FileInfo) (uid, gid uint32, ok bool) {
+	if st, _ := fi.Sys().(*syscall.Stat_t); st != nil {
+		return uint32(st.Uid), uint32(st.Gid), true
+	}
+	return 0, 0, false
+}
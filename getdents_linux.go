@@ -0,0 +1,77 @@
+//go:build linux
+
+package fastwalk
+
+import (
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// minDirentBufSize is the initial size of the getdents64 buffer. The
+	// buffer must be at least a block long (see blockSize).
+	minDirentBufSize = blockSize
+
+	// maxDirentBufSize is the maximum size of the getdents64 buffer.
+	maxDirentBufSize = 256 * 1024
+)
+
+// A direntBuffer reads directory entries with getdents64(2). Reading starts
+// with a small buffer that doubles in size each time a read fills more than
+// half of it, so that small directories use little memory and large
+// directories require fewer syscalls. The memory is pooled and reused
+// across directories.
+type direntBuffer struct {
+	buf  *[]byte // from direntBufPool
+	size int     // number of bytes to read at a time
+}
+
+var direntBufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, minDirentBufSize)
+		return &b
+	},
+}
+
+// read reads the next directory entries from fd. The returned slice is only
+// valid until the next call to read.
+func (b *direntBuffer) read(fd int) ([]byte, error) {
+	if b.buf == nil {
+		b.buf = direntBufPool.Get().(*[]byte)
+		b.size = minDirentBufSize
+	}
+	if len(*b.buf) < b.size {
+		*b.buf = make([]byte, b.size)
+	}
+	buf := (*b.buf)[:b.size]
+	n, err := getdents(fd, buf)
+	if err != nil {
+		return nil, err
+	}
+	if n > b.size/2 && b.size < maxDirentBufSize {
+		b.size *= 2
+	}
+	return buf[:n], nil
+}
+
+// release returns the buffer to the pool.
+func (b *direntBuffer) release() {
+	if b.buf != nil {
+		direntBufPool.Put(b.buf)
+		b.buf = nil
+	}
+}
+
+func getdents(fd int, buf []byte) (int, error) {
+	for {
+		n, _, errno := syscall.Syscall(syscall.SYS_GETDENTS64, uintptr(fd),
+			uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+		if errno == 0 {
+			return int(n), nil
+		}
+		if errno != syscall.EINTR {
+			return 0, errno
+		}
+	}
+}
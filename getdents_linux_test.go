@@ -0,0 +1,112 @@
+//go:build linux
+
+package fastwalk
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/charlievieth/fastwalk/internal/dirent"
+)
+
+func createDirentTestDir(t testing.TB, n int) string {
+	dir := t.TempDir()
+	for i := 0; i < n; i++ {
+		f, err := os.Create(filepath.Join(dir, "file_"+strings.Repeat("x", i%64)+strconv.Itoa(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	return dir
+}
+
+// readDirentNames reads the names of the entries in dir using read.
+func readDirentNames(t testing.TB, dir string, read func(fd int) ([]byte, error)) int {
+	fd, err := open(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fd)
+	count := 0
+	for {
+		buf, err := read(fd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(buf) == 0 {
+			return count
+		}
+		for len(buf) > 0 {
+			consumed, name, _ := dirent.Parse(buf)
+			if consumed <= 0 {
+				t.Fatal("failed to parse dirent")
+			}
+			buf = buf[consumed:]
+			if name != "" && name != "." && name != ".." {
+				count++
+			}
+		}
+	}
+}
+
+func TestDirentBuffer(t *testing.T) {
+	for _, test := range []struct {
+		files   int
+		minSize int
+		maxSize int
+	}{
+		{0, minDirentBufSize, minDirentBufSize},
+		{10, minDirentBufSize, minDirentBufSize},
+		{500, 2 * minDirentBufSize, maxDirentBufSize},
+	} {
+		dir := createDirentTestDir(t, test.files)
+		var db direntBuffer
+		n := readDirentNames(t, dir, db.read)
+		if n != test.files {
+			t.Errorf("%d: read %d entries; want: %d", test.files, n, test.files)
+		}
+		if db.size < test.minSize || db.size > test.maxSize {
+			t.Errorf("%d: buffer size = %d; want: %d..%d", test.files, db.size, test.minSize, test.maxSize)
+		}
+		db.release()
+		if db.buf != nil {
+			t.Error("release did not clear the buffer")
+		}
+	}
+}
+
+// BenchmarkReadDirent compares reading directories with a fixed 8KB buffer
+// and syscall.ReadDirent to the adaptive getdents64 direntBuffer.
+func BenchmarkReadDirent(b *testing.B) {
+	for _, files := range []int{8, 512, 16384} {
+		dir := createDirentTestDir(b, files)
+		b.Run(strconv.Itoa(files), func(b *testing.B) {
+			b.Run("Fixed", func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					buf := make([]byte, 8192)
+					readDirentNames(b, dir, func(fd int) ([]byte, error) {
+						n, err := syscall.ReadDirent(fd, buf)
+						if n < 0 {
+							n = 0
+						}
+						return buf[:n], err
+					})
+				}
+			})
+			b.Run("Adaptive", func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					var db direntBuffer
+					readDirentNames(b, dir, db.read)
+					db.release()
+				}
+			})
+		})
+	}
+}
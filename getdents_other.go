@@ -0,0 +1,34 @@
+//go:build aix || dragonfly || freebsd || (js && wasm) || netbsd || openbsd || solaris
+
+package fastwalk
+
+import "syscall"
+
+// A direntBuffer reads directory entries with syscall.ReadDirent.
+type direntBuffer struct {
+	buf [blockSize]byte
+}
+
+// read reads the next directory entries from fd. The returned slice is only
+// valid until the next call to read.
+func (b *direntBuffer) read(fd int) ([]byte, error) {
+	n, err := readDirent(fd, b.buf[:])
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		n = 0
+	}
+	return b.buf[:n], nil
+}
+
+func (b *direntBuffer) release() {}
+
+func readDirent(fd int, buf []byte) (n int, err error) {
+	for {
+		nbuf, err := syscall.ReadDirent(fd, buf)
+		if err != syscall.EINTR {
+			return nbuf, err
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package fastwalk
+
+import (
+	"sync"
+	"time"
+)
+
+// A Throttler can pause a walk, for example while the system is under heavy
+// load or outside of a maintenance window. See [Config.Throttle].
+//
+// Since Config is comparable, implementations should be comparable types
+// (such as pointers) otherwise comparing Configs will panic.
+type Throttler interface {
+	// Throttle is called by a worker before it reads the directory dir
+	// and may block to delay reading it. It is called concurrently by
+	// multiple workers.
+	Throttle(dir string)
+}
+
+// A rateLimiter is a token bucket that allows rate events per second with
+// bursts of up to one second's worth of events.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:   float64(rate),
+		burst:  float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until an event is allowed. Tokens are reserved before
+// sleeping so that concurrent callers are spaced out rather than woken at
+// the same time. It is safe to call wait on a nil rateLimiter.
+func (l *rateLimiter) wait() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens--
+	var d time.Duration
+	if l.tokens < 0 {
+		d = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// throttle is called before the directory dir is read and enforces
+// Config.MaxDirsPerSecond and Config.Throttle. It also blocks while the
+// walk is paused and returns an error if it was stopped (see Start).
+func (w *walker) throttle(dir string) error {
+	if w.handle != nil {
+		if err := w.handle.wait(); err != nil {
+			return err
+		}
+	}
+	w.dirLimit.wait()
+	if w.throttler != nil {
+		w.throttler.Throttle(dir)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package fastwalk
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	const rate = 1000
+	l := newRateLimiter(rate)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rate/2; j++ {
+				l.wait()
+			}
+		}()
+	}
+	wg.Wait()
+	// The first second's worth of events is a burst, the remaining
+	// rate events must take at least one second.
+	if d := time.Since(start); d < 900*time.Millisecond {
+		t.Errorf("%d events at %d/s took %s; want: ≥ 1s", 2*rate, rate, d)
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	if l := newRateLimiter(0); l != nil {
+		t.Fatal("newRateLimiter(0) should return nil")
+	}
+	var l *rateLimiter
+	l.wait() // must not panic
+}
+
+func TestRateLimiterSpilledEntry(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	it := walkItem{
+		dir:  filepath.Join(dir, "sub"),
+		info: newDirEntryType(dir, "sub", os.ModeDir, 1, nil),
+	}
+	l := newRateLimiter(1)
+	it, n := parseSpillRecord(appendSpillRecord(nil, it), l)
+	if n == 0 {
+		t.Fatal("failed to parse spill record")
+	}
+	if _, err := it.info.Info(); err != nil {
+		t.Fatal(err)
+	}
+	l.mu.Lock()
+	tokens := l.tokens
+	l.mu.Unlock()
+	if tokens >= 0.5 {
+		t.Errorf("Info() of a spilled entry did not use the rate limiter: tokens = %.2f", tokens)
+	}
+}
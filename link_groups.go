@@ -0,0 +1,105 @@
+package fastwalk
+
+import (
+	"io/fs"
+	"sort"
+	"sync"
+)
+
+// A LinkGroup is a set of paths that refer to the same file, either because
+// they are hard links to it or because they are symbolic links to it.
+//
+// Since directories are walked in parallel, which path is seen first, and
+// is reported as Path, is not deterministic.
+type LinkGroup struct {
+	Key     FileKey  // Key identifying the file
+	Path    string   // First path seen that refers to the file
+	Aliases []string // All other paths that refer to the file
+}
+
+type linkGroupMap struct {
+	mu     sync.Mutex
+	paths  map[FileKey]string     // files seen once
+	groups map[FileKey]*LinkGroup // files seen more than once
+}
+
+// LinkGroups records the paths of every file it sees grouped by the file
+// they refer to. It implements the [Entryer] interface and is intended
+// to be used with [IgnoreDuplicateFilesFilter] so that the WalkDirFunc is
+// only called for the first path to a file, while every other path to it
+// is still recorded and available from Groups once the walk completes.
+//
+// This is useful for programs like disk usage or backup tools that need
+// to count the size of each file once while still listing all of its names.
+//
+//	groups := fastwalk.NewLinkGroups()
+//	err := fastwalk.Walk(conf, root, fastwalk.IgnoreDuplicateFilesFilter(walkFn, groups))
+//	for _, g := range groups.Groups() {
+//		fmt.Println(g.Path, g.Aliases)
+//	}
+//
+// The key and first path of every file are recorded, a LinkGroup is only
+// allocated for files that are seen more than once.
+//
+// Like [BloomEntryFilter], errors that occur while identifying a file are
+// treated as duplicates (see [Entryer]) and the path is not recorded.
+type LinkGroups struct {
+	// Use multiple maps to reduce lock contention (see EntryFilter).
+	maps [8]linkGroupMap
+}
+
+// NewLinkGroups returns a new LinkGroups.
+func NewLinkGroups() *LinkGroups {
+	return new(LinkGroups)
+}
+
+// Entry records path and returns if the file it refers to has been seen before.
+func (g *LinkGroups) Entry(path string, de fs.DirEntry) (seen bool) {
+	key, err := entryKey(path, de)
+	if err != nil {
+		return true // treat errors as duplicate files
+	}
+	m := &g.maps[key.hash()%uint64(len(g.maps))]
+	m.mu.Lock()
+	if lg := m.groups[key]; lg != nil {
+		lg.Aliases = append(lg.Aliases, path)
+		seen = true
+	} else if first, ok := m.paths[key]; ok {
+		delete(m.paths, key)
+		if m.groups == nil {
+			m.groups = make(map[FileKey]*LinkGroup)
+		}
+		m.groups[key] = &LinkGroup{Key: key, Path: first, Aliases: []string{path}}
+		seen = true
+	} else {
+		if m.paths == nil {
+			m.paths = make(map[FileKey]string)
+		}
+		m.paths[key] = path
+	}
+	m.mu.Unlock()
+	return seen
+}
+
+// Groups returns the files that were seen via more than one path sorted by
+// the first path that referred to them. The aliases of each group are sorted
+// lexically.
+//
+// Groups should be called after Walk returns.
+func (g *LinkGroups) Groups() []LinkGroup {
+	var groups []LinkGroup
+	for i := range g.maps {
+		m := &g.maps[i]
+		m.mu.Lock()
+		for _, lg := range m.groups {
+			aliases := append([]string(nil), lg.Aliases...)
+			sort.Strings(aliases)
+			groups = append(groups, LinkGroup{Key: lg.Key, Path: lg.Path, Aliases: aliases})
+		}
+		m.mu.Unlock()
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Path < groups[j].Path
+	})
+	return groups
+}
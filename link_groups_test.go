@@ -0,0 +1,102 @@
+package fastwalk_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/charlievieth/fastwalk"
+)
+
+func TestLinkGroups(t *testing.T) {
+	tempdir := t.TempDir()
+	testCreateFiles(t, tempdir, map[string]string{
+		"a.txt":       "a",
+		"b.txt":       "b",
+		"sub/c.txt":   "c",
+		"sub/symlink": "LINK:../a.txt",
+	})
+	src := filepath.Join(tempdir, "src")
+	if err := os.Link(filepath.Join(src, "a.txt"), filepath.Join(src, "sub", "hardlink")); err != nil {
+		t.Skip("hard links appear to be unsupported:", err)
+	}
+
+	var mu sync.Mutex
+	var visited []string
+	groups := fastwalk.NewLinkGroups()
+	walkFn := fastwalk.IgnoreDuplicateFilesFilter(func(path string, de fs.DirEntry, err error) error {
+		requireNoError(t, err)
+		if !de.IsDir() {
+			mu.Lock()
+			visited = append(visited, path)
+			mu.Unlock()
+		}
+		return nil
+	}, groups)
+	if err := fastwalk.Walk(nil, src, walkFn); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each file should be visited only once
+	if len(visited) != 3 {
+		t.Errorf("visited %d files want: %d: %q", len(visited), 3, visited)
+	}
+
+	gs := groups.Groups()
+	if len(gs) != 1 {
+		t.Fatalf("got %d groups want: %d: %+v", len(gs), 1, gs)
+	}
+	got := append([]string{gs[0].Path}, gs[0].Aliases...)
+	sort.Strings(got)
+	want := []string{
+		filepath.Join(src, "a.txt"),
+		filepath.Join(src, "sub", "hardlink"),
+		filepath.Join(src, "sub", "symlink"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("group paths: got: %q want: %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("group paths: got: %q want: %q", got, want)
+			break
+		}
+	}
+}
+
+// Test that a file visited before a symbolic link to it is recorded so that
+// the symbolic link is a duplicate.
+func TestLinkGroupsFileBeforeSymlink(t *testing.T) {
+	tempdir := t.TempDir()
+	testCreateFiles(t, tempdir, map[string]string{
+		"a.txt":   "a",
+		"symlink": "LINK:a.txt",
+	})
+	src := filepath.Join(tempdir, "src")
+	groups := fastwalk.NewLinkGroups()
+	for i, name := range []string{"a.txt", "symlink"} {
+		path := filepath.Join(src, name)
+		fi, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen := groups.Entry(path, fs.FileInfoToDirEntry(fi)); seen != (i == 1) {
+			t.Errorf("Entry(%q) = %t; want: %t", path, seen, i == 1)
+		}
+	}
+	gs := groups.Groups()
+	want := []fastwalk.LinkGroup{{
+		Path:    filepath.Join(src, "a.txt"),
+		Aliases: []string{filepath.Join(src, "symlink")},
+	}}
+	if len(gs) == 1 {
+		want[0].Key = gs[0].Key
+	}
+	if !reflect.DeepEqual(gs, want) {
+		t.Errorf("Groups() = %+v; want: %+v", gs, want)
+	}
+}
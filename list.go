@@ -0,0 +1,59 @@
+package fastwalk
+
+import "io/fs"
+
+// An Entry is a file or directory found by [List].
+type Entry struct {
+	Path string // path of the entry, as passed to the Walk callback
+	DirEntry
+}
+
+// List walks the file tree rooted at root and returns all of its entries,
+// including root, in a deterministic order.
+//
+// Entries are returned in the order they are visited by an ordered walk (see
+// Config.Ordered): depth-first, with each directory immediately followed by
+// its contents and the entries of a directory ordered by conf.Sort, with
+// [SortNone] treated as [SortLexical]. With lexical sorting this is the same
+// order as [filepath.WalkDir].
+//
+// All other Config options are respected. Directories that are not passed to
+// the callback because they do not match Config.Match are omitted, but their
+// contents are not.
+//
+// The walk stops at the first error, which is returned along with a nil
+// slice.
+func List(conf *Config, root string) ([]Entry, error) {
+	var ents []Entry
+	err := ListSorted(conf, root, func(e Entry) error {
+		ents = append(ents, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ents, nil
+}
+
+// ListSorted walks the file tree rooted at root and calls fn for each entry,
+// serially and in the same order as [List] returns them. Entries are passed
+// to fn as soon as their position in the order is known, so unlike List
+// the memory used does not grow with the size of the tree.
+//
+// If fn returns [filepath.SkipDir] when invoked on a directory the contents
+// of that directory are skipped, if it is invoked on a file the remaining
+// entries in the file's directory are skipped. Any other error stops
+// ListSorted and is returned.
+func ListSorted(conf *Config, root string, fn func(e Entry) error) error {
+	c := DefaultConfig
+	if conf != nil {
+		c = *conf
+	}
+	c.Ordered = true
+	return Walk(&c, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return fn(Entry{Path: path, DirEntry: d.(DirEntry)})
+	})
+}
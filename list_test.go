@@ -0,0 +1,187 @@
+package fastwalk_test
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"testing"
+
+	"github.com/charlievieth/fastwalk"
+)
+
+var listTestFiles = map[string]string{
+	"a.txt":         "a",
+	"a/b.txt":       "b",
+	"a/c/d.txt":     "d",
+	"a/c/e/f.txt":   "f",
+	"a-b/g.txt":     "g",
+	"b/h.txt":       "h",
+	"b/i/j.txt":     "j",
+	"b/z.txt":       "z",
+	"c/k/l/m/n.txt": "n",
+}
+
+// createListTestFiles creates files in a new temporary directory and
+// returns the path of the directory.
+func createListTestFiles(t *testing.T, files map[string]string) string {
+	tempdir := t.TempDir()
+	testCreateFiles(t, tempdir, files)
+	return filepath.Join(tempdir, "src")
+}
+
+func listPaths(t *testing.T, ents []fastwalk.Entry, root string) []string {
+	paths := make([]string, len(ents))
+	for i, e := range ents {
+		rel, err := filepath.Rel(root, e.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths[i] = filepath.ToSlash(rel)
+	}
+	return paths
+}
+
+func TestList(t *testing.T) {
+	tempdir := createListTestFiles(t, listTestFiles)
+
+	var want []string
+	err := filepath.WalkDir(tempdir, func(path string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(tempdir, path)
+		want = append(want, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Run multiple times since the order of a parallel walk varies.
+	for i := 0; i < 5; i++ {
+		ents, err := fastwalk.List(&fastwalk.Config{NumWorkers: 4}, tempdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := listPaths(t, ents, tempdir)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("List order:\ngot:  %q\nwant: %q", got, want)
+		}
+	}
+}
+
+func TestList_SortMode(t *testing.T) {
+	tempdir := createListTestFiles(t, map[string]string{
+		"b.txt":   "b",
+		"a/c.txt": "c",
+		"z/d.txt": "d",
+		"y.txt":   "y",
+		"b.link":  "LINK:b.txt",
+	})
+	tests := []struct {
+		mode fastwalk.SortMode
+		want []string
+	}{
+		{fastwalk.SortLexical, []string{".", "a", "a/c.txt", "b.link", "b.txt", "y.txt", "z", "z/d.txt"}},
+		{fastwalk.SortFilesFirst, []string{".", "b.txt", "y.txt", "b.link", "a", "a/c.txt", "z", "z/d.txt"}},
+		{fastwalk.SortDirsFirst, []string{".", "a", "a/c.txt", "z", "z/d.txt", "b.txt", "y.txt", "b.link"}},
+	}
+	for _, test := range tests {
+		t.Run(test.mode.String(), func(t *testing.T) {
+			ents, err := fastwalk.List(&fastwalk.Config{Sort: test.mode}, tempdir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := listPaths(t, ents, tempdir)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got:  %q\nwant: %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestList_Match(t *testing.T) {
+	tempdir := createListTestFiles(t, listTestFiles)
+	conf := fastwalk.Config{Match: regexp.MustCompile(`\.txt$`)}
+	ents, err := fastwalk.List(&conf, tempdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := listPaths(t, ents, tempdir)
+	want := []string{
+		"a/b.txt", "a/c/d.txt", "a/c/e/f.txt", "a-b/g.txt", "a.txt",
+		"b/h.txt", "b/i/j.txt", "b/z.txt", "c/k/l/m/n.txt",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestList_Follow(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks to directories are not reliably supported")
+	}
+	tempdir := createListTestFiles(t, map[string]string{
+		"d/a.txt":   "a",
+		"d/e/b.txt": "b",
+		"link":      "LINK:d",
+	})
+	ents, err := fastwalk.List(&fastwalk.Config{Follow: true}, tempdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := listPaths(t, ents, tempdir)
+	want := []string{
+		".", "d", "d/a.txt", "d/e", "d/e/b.txt",
+		"link", "link/a.txt", "link/e", "link/e/b.txt",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestListSorted(t *testing.T) {
+	tempdir := createListTestFiles(t, listTestFiles)
+
+	var got []string
+	err := fastwalk.ListSorted(nil, tempdir, func(e fastwalk.Entry) error {
+		rel, err := filepath.Rel(tempdir, e.Path)
+		if err != nil {
+			return err
+		}
+		got = append(got, filepath.ToSlash(rel))
+		switch e.Name() {
+		case "c":
+			return filepath.SkipDir // skip directory
+		case "h.txt":
+			return filepath.SkipDir // skip the rest of "b"
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		".", "a", "a/b.txt", "a/c", "a-b", "a-b/g.txt", "a.txt",
+		"b", "b/h.txt", "c",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:  %q\nwant: %q", got, want)
+	}
+
+	errStop := errors.New("stop")
+	n := 0
+	err = fastwalk.ListSorted(nil, tempdir, func(e fastwalk.Entry) error {
+		n++
+		if n == 3 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop || n != 3 {
+		t.Errorf("got error %v after %d entries want: %v after %d", err, n, errStop, 3)
+	}
+}
@@ -0,0 +1,95 @@
+package fastwalk
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// A pathMatcher wraps a regexp with a fast path that rejects most paths
+// without running the regexp engine by checking literal strings that
+// every match must contain.
+type pathMatcher struct {
+	re       *regexp.Regexp
+	prefix   string // literal prefix of an expression anchored at the start
+	suffix   string // literal suffix of an expression anchored at the end (e.g. an extension)
+	contains string // longest literal that any match must contain
+}
+
+func newPathMatcher(re *regexp.Regexp) *pathMatcher {
+	if re == nil {
+		return nil
+	}
+	m := &pathMatcher{re: re}
+	// Compile uses the Perl flags. CompilePOSIX does not, so "^" and "$"
+	// may match at line boundaries, which is handled by match.
+	st, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return m // should not happen since re is already compiled
+	}
+	st = st.Simplify()
+	subs := []*syntax.Regexp{st}
+	if st.Op == syntax.OpConcat {
+		subs = st.Sub
+	}
+	isLiteral := func(x *syntax.Regexp) bool {
+		return x.Op == syntax.OpLiteral && x.Flags&syntax.FoldCase == 0
+	}
+	n := len(subs)
+	if n >= 2 && subs[0].Op == syntax.OpBeginText && isLiteral(subs[1]) {
+		m.prefix = string(subs[1].Rune)
+	}
+	if n >= 2 && subs[n-1].Op == syntax.OpEndText && isLiteral(subs[n-2]) {
+		m.suffix = string(subs[n-2].Rune)
+	}
+	for _, x := range subs {
+		if isLiteral(x) && len(string(x.Rune)) > len(m.contains) {
+			m.contains = string(x.Rune)
+		}
+	}
+	if m.contains == m.prefix || m.contains == m.suffix {
+		m.contains = "" // already checked
+	}
+	return m
+}
+
+func (m *pathMatcher) match(path string) bool {
+	// The anchors of a POSIX expression also match at line boundaries, so
+	// the prefix and suffix are only checked if path is a single line.
+	if (m.prefix != "" || m.suffix != "") && strings.IndexByte(path, '\n') == -1 &&
+		(!strings.HasPrefix(path, m.prefix) || !strings.HasSuffix(path, m.suffix)) {
+		return false
+	}
+	if m.contains != "" && !strings.Contains(path, m.contains) {
+		return false
+	}
+	return m.re.MatchString(path)
+}
+
+// relPath returns path relative to the root of the walk using forward slashes
+// as the path separator. The relative path of the root is ".".
+func (w *walker) relPath(path string) string {
+	if len(path) <= len(w.root) {
+		return "."
+	}
+	rel := path[len(w.root):]
+	if os.IsPathSeparator(rel[0]) {
+		rel = rel[1:]
+	}
+	return filepath.ToSlash(rel)
+}
+
+// matchPath reports if the entry at path should be passed to the user's
+// callback (Config.Match) and if it should be pruned (Config.Prune).
+func (w *walker) matchPath(path string) (match, prune bool) {
+	if w.match == nil && w.prune == nil {
+		return true, false
+	}
+	rel := w.relPath(path)
+	if w.prune != nil && w.prune.match(rel) {
+		return false, true
+	}
+	return w.match == nil || w.match.match(rel), false
+}
@@ -0,0 +1,109 @@
+package fastwalk
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestPathMatcher(t *testing.T) {
+	tests := []struct {
+		expr     string
+		prefix   string
+		suffix   string
+		contains string
+		match    []string
+		noMatch  []string
+	}{
+		{
+			expr:    `^src/.*`,
+			prefix:  "src/",
+			match:   []string{"src/a.go", "src/b/c.go"},
+			noMatch: []string{".", "src", "a/src/b.go"},
+		},
+		{
+			expr:    `\.go$`,
+			suffix:  ".go",
+			match:   []string{"a.go", "src/b.go"},
+			noMatch: []string{"a.go.txt", "ago"},
+		},
+		{
+			expr:     `node_modules/.*\.js$`,
+			suffix:   ".js",
+			contains: "node_modules/",
+			match:    []string{"node_modules/a.js", "a/node_modules/b/c.js"},
+			noMatch:  []string{"node_modules", "a/b.js"},
+		},
+		{
+			expr:    `(?i)\.GO$`,
+			match:   []string{"a.go", "a.GO"},
+			noMatch: []string{"a.txt"},
+		},
+		{
+			expr:    `^a|b$`,
+			match:   []string{"abc", "cab"},
+			noMatch: []string{"cat"},
+		},
+	}
+	for _, test := range tests {
+		m := newPathMatcher(regexp.MustCompile(test.expr))
+		if m.prefix != test.prefix || m.suffix != test.suffix || m.contains != test.contains {
+			t.Errorf("%q: prefix, suffix, contains = %q, %q, %q; want: %q, %q, %q",
+				test.expr, m.prefix, m.suffix, m.contains,
+				test.prefix, test.suffix, test.contains)
+		}
+		for _, s := range test.match {
+			if !m.match(s) {
+				t.Errorf("%q: failed to match: %q", test.expr, s)
+			}
+		}
+		for _, s := range test.noMatch {
+			if m.match(s) {
+				t.Errorf("%q: should not match: %q", test.expr, s)
+			}
+		}
+	}
+	if newPathMatcher(nil) != nil {
+		t.Error("newPathMatcher(nil) should return nil")
+	}
+}
+
+// The anchors of POSIX expressions match at line boundaries.
+func TestPathMatcherPOSIX(t *testing.T) {
+	for _, test := range []struct {
+		expr    string
+		match   []string
+		noMatch []string
+	}{
+		{`^src/`, []string{"src/a", "a\nsrc/b"}, []string{"a/src/b"}},
+		{`\.go$`, []string{"a.go", "a.go\nb"}, []string{"a.go.txt"}},
+	} {
+		re := regexp.MustCompilePOSIX(test.expr)
+		m := newPathMatcher(re)
+		for _, s := range test.match {
+			if !m.match(s) {
+				t.Errorf("%q: failed to match: %q", test.expr, s)
+			}
+		}
+		for _, s := range test.noMatch {
+			if m.match(s) {
+				t.Errorf("%q: should not match: %q", test.expr, s)
+			}
+		}
+	}
+}
+
+func BenchmarkPathMatcher(b *testing.B) {
+	re := regexp.MustCompile(`.*\.go$`)
+	m := newPathMatcher(re)
+	paths := []string{"src/a.txt", "src/b/c.md", "d/e/f/g.go", "README"}
+	b.Run("Regexp", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			re.MatchString(paths[i%len(paths)])
+		}
+	})
+	b.Run("PathMatcher", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			m.match(paths[i%len(paths)])
+		}
+	})
+}
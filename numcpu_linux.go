@@ -0,0 +1,239 @@
+//go:build linux
+
+package fastwalk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// cgroupCPULimit returns the number of CPUs that the cgroup CPU quota of
+// the current process allows it to use, rounded up, or -1 if there is no
+// quota. Both cgroup v2 (cpu.max) and v1 (cpu.cfs_quota_us and
+// cpu.cfs_period_us) are supported.
+//
+// In containers runtime.NumCPU reports the number of CPUs of the host, so
+// without this a container limited to 2 CPUs on a 64 core host would use
+// 32 workers and be throttled.
+//
+// The limit is read once and cached since /proc/self/mountinfo can be large
+// and DefaultNumWorkers is called by every walk that does not set
+// Config.NumWorkers.
+var cgroupCPULimit = sync.OnceValue(func() int {
+	return readCgroupCPULimit("/")
+})
+
+// readCgroupCPULimit implements cgroupCPULimit. All files are read relative
+// to root, which allows for testing with fixtures.
+func readCgroupCPULimit(root string) int {
+	cgroups, err := os.ReadFile(filepath.Join(root, "proc/self/cgroup"))
+	if err != nil {
+		return -1
+	}
+	mountinfo, err := os.ReadFile(filepath.Join(root, "proc/self/mountinfo"))
+	if err != nil {
+		return -1
+	}
+	limit := -1
+	for _, m := range parseCgroupMounts(string(mountinfo)) {
+		path, ok := cgroupPath(string(cgroups), m.v2)
+		if !ok {
+			continue
+		}
+		// Make the path relative to the root of the mount, which is
+		// not "/" when the mount is a subtree of the hierarchy.
+		if m.root != "/" {
+			if path != m.root && !strings.HasPrefix(path, m.root+"/") {
+				continue
+			}
+			path = path[len(m.root):]
+		}
+		dir := filepath.Join(root, m.point, path)
+		if n := cgroupDirLimit(filepath.Join(root, m.point), dir, m.v2); n > 0 && (limit < 0 || n < limit) {
+			limit = n
+		}
+	}
+	return limit
+}
+
+// A cgroupMount is a mount of the cgroup v2 hierarchy or a cgroup v1
+// hierarchy with the cpu controller.
+type cgroupMount struct {
+	root  string // root of the mount within the hierarchy
+	point string // mount point
+	v2    bool
+}
+
+// parseCgroupMounts returns the cgroup mounts found in the contents of
+// /proc/self/mountinfo, which has lines of the form:
+//
+//	36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+//	(1)(2)(3)   (4)   (5)      (6)      (7)   (8) (9)   (10)         (11)
+//
+// See proc(5) for details.
+func parseCgroupMounts(mountinfo string) []cgroupMount {
+	var mounts []cgroupMount
+	for _, line := range strings.Split(mountinfo, "\n") {
+		i := strings.Index(line, " - ")
+		if i == -1 {
+			continue
+		}
+		fields := strings.Fields(line[:i])
+		super := strings.Fields(line[i+len(" - "):])
+		if len(fields) < 5 || len(super) < 1 {
+			continue
+		}
+		m := cgroupMount{
+			root:  unescapeMountPath(fields[3]),
+			point: unescapeMountPath(fields[4]),
+		}
+		switch super[0] {
+		case "cgroup2":
+			m.v2 = true
+		case "cgroup":
+			if len(super) < 3 || !hasCgroupController(super[2], "cpu") {
+				continue
+			}
+		default:
+			continue
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts
+}
+
+// cgroupPath returns the path of the process's cgroup in the v2 hierarchy or
+// the v1 hierarchy with the cpu controller from the contents of
+// /proc/self/cgroup, which has lines of the form:
+//
+//	hierarchy-ID:controller-list:cgroup-path
+func cgroupPath(cgroups string, v2 bool) (string, bool) {
+	for _, line := range strings.Split(cgroups, "\n") {
+		id, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		controllers, path, ok := strings.Cut(rest, ":")
+		if !ok {
+			continue
+		}
+		if v2 {
+			if id == "0" && controllers == "" {
+				return path, true
+			}
+		} else if hasCgroupController(controllers, "cpu") {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// hasCgroupController reports if the comma separated list of controllers or
+// mount options contains name.
+func hasCgroupController(list, name string) bool {
+	for list != "" {
+		var s string
+		s, list, _ = strings.Cut(list, ",")
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// cgroupDirLimit returns the smallest CPU limit of the cgroup at dir and its
+// parents up to the mount point mnt.
+func cgroupDirLimit(mnt, dir string, v2 bool) int {
+	limit := -1
+	for {
+		var n int
+		if v2 {
+			n = readCPUMax(dir)
+		} else {
+			n = readCFSQuota(dir)
+		}
+		if n > 0 && (limit < 0 || n < limit) {
+			limit = n
+		}
+		if len(dir) <= len(mnt) {
+			return limit
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// readCPUMax returns the CPU limit from the cgroup v2 file "cpu.max", which
+// contains "$MAX $PERIOD" where $MAX may be "max" for no limit.
+func readCPUMax(dir string) int {
+	data, err := os.ReadFile(filepath.Join(dir, "cpu.max"))
+	if err != nil {
+		return -1
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 || fields[0] == "max" {
+		return -1
+	}
+	return cpuLimit(parseCgroupInt(fields[0]), parseCgroupInt(fields[1]))
+}
+
+// readCFSQuota returns the CPU limit from the cgroup v1 files
+// "cpu.cfs_quota_us", which is -1 for no limit, and "cpu.cfs_period_us".
+func readCFSQuota(dir string) int {
+	quota, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_quota_us"))
+	if err != nil {
+		return -1
+	}
+	period, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_period_us"))
+	if err != nil {
+		return -1
+	}
+	return cpuLimit(parseCgroupInt(strings.TrimSpace(string(quota))),
+		parseCgroupInt(strings.TrimSpace(string(period))))
+}
+
+// cpuLimit returns quota / period rounded up or -1 if either is invalid.
+func cpuLimit(quota, period int64) int {
+	if quota <= 0 || period <= 0 {
+		return -1
+	}
+	return int((quota + period - 1) / period)
+}
+
+// parseCgroupInt parses a non-negative decimal integer and returns -1 if s
+// is not valid. We avoid the dependency on strconv (see itoa).
+func parseCgroupInt(s string) int64 {
+	if s == "" || len(s) > 18 {
+		return -1
+	}
+	var n int64
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return -1
+		}
+		n = n*10 + int64(c-'0')
+	}
+	return n
+}
+
+// unescapeMountPath replaces the octal escapes ("\040" for space, etc.)
+// that the kernel uses for special characters in mountinfo paths.
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && isOctal(s[i+1]) && isOctal(s[i+2]) && isOctal(s[i+3]) {
+			b = append(b, (s[i+1]-'0')<<6|(s[i+2]-'0')<<3|(s[i+3]-'0'))
+			i += 3
+			continue
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
+
+func isOctal(c byte) bool { return '0' <= c && c <= '7' }
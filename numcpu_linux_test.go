@@ -0,0 +1,176 @@
+//go:build linux
+
+package fastwalk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	mountinfoV2 = `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+25 22 0:23 / /sys/fs/cgroup rw,nosuid,nodev,noexec,relatime shared:9 - cgroup2 cgroup2 rw,nsdelegate
+`
+	mountinfoV1 = `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+32 22 0:28 / /sys/fs/cgroup ro,nosuid,nodev,noexec shared:9 - tmpfs tmpfs ro,mode=755
+33 32 0:29 / /sys/fs/cgroup/cpu,cpuacct rw,nosuid,nodev,noexec,relatime shared:10 - cgroup cgroup rw,cpu,cpuacct
+34 32 0:30 / /sys/fs/cgroup/memory rw,nosuid,nodev,noexec,relatime shared:11 - cgroup cgroup rw,memory
+`
+	// Docker with cgroup v1: the mount is the container's subtree.
+	mountinfoV1Container = `22 1 8:1 / / rw,relatime - overlay overlay rw
+33 32 0:29 /docker/abc /sys/fs/cgroup/cpu,cpuacct ro,nosuid,nodev,noexec,relatime - cgroup cgroup rw,cpu,cpuacct
+`
+	mountinfoHybrid = mountinfoV1 + `35 32 0:31 / /sys/fs/cgroup/unified rw,nosuid,nodev,noexec,relatime shared:12 - cgroup2 cgroup2 rw
+`
+	mountinfoEscaped = `25 22 0:23 / /sys/fs/cgroup\040v2 rw - cgroup2 cgroup2 rw
+`
+
+	cgroupV2 = "0::/kubepods/pod1/c1\n"
+	cgroupV1 = `12:memory:/kubepods/pod1/c1
+4:cpu,cpuacct:/kubepods/pod1/c1
+1:name=systemd:/kubepods/pod1/c1
+`
+	cgroupV1Container = "4:cpu,cpuacct:/docker/abc\n"
+	cgroupHybrid      = cgroupV1 + "0::/kubepods/pod1/c1\n"
+)
+
+func TestCgroupCPULimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  int
+	}{
+		{
+			name: "V2",
+			files: map[string]string{
+				"proc/self/mountinfo":                       mountinfoV2,
+				"proc/self/cgroup":                          cgroupV2,
+				"sys/fs/cgroup/kubepods/pod1/c1/cpu.max":    "200000 100000\n",
+				"sys/fs/cgroup/kubepods/pod1/cpu.max":       "max 100000\n",
+				"sys/fs/cgroup/kubepods/cpu.max":            "max 100000\n",
+				"sys/fs/cgroup/kubepods/pod1/c1/cpu.weight": "100\n",
+			},
+			want: 2,
+		},
+		{
+			name: "V2RoundUp",
+			files: map[string]string{
+				"proc/self/mountinfo":                    mountinfoV2,
+				"proc/self/cgroup":                       cgroupV2,
+				"sys/fs/cgroup/kubepods/pod1/c1/cpu.max": "150000 100000\n",
+			},
+			want: 2,
+		},
+		{
+			name: "V2NoLimit",
+			files: map[string]string{
+				"proc/self/mountinfo":                    mountinfoV2,
+				"proc/self/cgroup":                       cgroupV2,
+				"sys/fs/cgroup/kubepods/pod1/c1/cpu.max": "max 100000\n",
+			},
+			want: -1,
+		},
+		{
+			name: "V2Parent",
+			files: map[string]string{
+				"proc/self/mountinfo":                    mountinfoV2,
+				"proc/self/cgroup":                       cgroupV2,
+				"sys/fs/cgroup/kubepods/pod1/c1/cpu.max": "max 100000\n",
+				"sys/fs/cgroup/kubepods/pod1/cpu.max":    "100000 100000\n",
+				"sys/fs/cgroup/kubepods/cpu.max":         "800000 100000\n",
+			},
+			want: 1,
+		},
+		{
+			name: "V2Escaped",
+			files: map[string]string{
+				"proc/self/mountinfo":                       mountinfoEscaped,
+				"proc/self/cgroup":                          cgroupV2,
+				"sys/fs/cgroup v2/kubepods/pod1/c1/cpu.max": "400000 100000\n",
+			},
+			want: 4,
+		},
+		{
+			name: "V1",
+			files: map[string]string{
+				"proc/self/mountinfo": mountinfoV1,
+				"proc/self/cgroup":    cgroupV1,
+				"sys/fs/cgroup/cpu,cpuacct/kubepods/pod1/c1/cpu.cfs_quota_us":  "300000\n",
+				"sys/fs/cgroup/cpu,cpuacct/kubepods/pod1/c1/cpu.cfs_period_us": "100000\n",
+			},
+			want: 3,
+		},
+		{
+			name: "V1NoLimit",
+			files: map[string]string{
+				"proc/self/mountinfo": mountinfoV1,
+				"proc/self/cgroup":    cgroupV1,
+				"sys/fs/cgroup/cpu,cpuacct/kubepods/pod1/c1/cpu.cfs_quota_us":  "-1\n",
+				"sys/fs/cgroup/cpu,cpuacct/kubepods/pod1/c1/cpu.cfs_period_us": "100000\n",
+			},
+			want: -1,
+		},
+		{
+			name: "V1Container",
+			files: map[string]string{
+				"proc/self/mountinfo":                         mountinfoV1Container,
+				"proc/self/cgroup":                            cgroupV1Container,
+				"sys/fs/cgroup/cpu,cpuacct/cpu.cfs_quota_us":  "50000\n",
+				"sys/fs/cgroup/cpu,cpuacct/cpu.cfs_period_us": "100000\n",
+			},
+			want: 1,
+		},
+		{
+			name: "Hybrid",
+			files: map[string]string{
+				"proc/self/mountinfo": mountinfoHybrid,
+				"proc/self/cgroup":    cgroupHybrid,
+				"sys/fs/cgroup/cpu,cpuacct/kubepods/pod1/c1/cpu.cfs_quota_us":  "600000\n",
+				"sys/fs/cgroup/cpu,cpuacct/kubepods/pod1/c1/cpu.cfs_period_us": "100000\n",
+				"sys/fs/cgroup/unified/kubepods/pod1/c1/cpu.max":               "200000 100000\n",
+			},
+			want: 2,
+		},
+		{
+			name: "Invalid",
+			files: map[string]string{
+				"proc/self/mountinfo":                    mountinfoV2,
+				"proc/self/cgroup":                       cgroupV2,
+				"sys/fs/cgroup/kubepods/pod1/c1/cpu.max": "2e5 100000\n",
+			},
+			want: -1,
+		},
+		{
+			name: "NoCgroup",
+			files: map[string]string{
+				"proc/self/mountinfo": mountinfoV2,
+			},
+			want: -1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, data := range test.files {
+				path := filepath.Join(root, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := readCgroupCPULimit(root); got != test.want {
+				t.Errorf("readCgroupCPULimit() = %d; want: %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCgroupCPULimitHost(t *testing.T) {
+	// Test that the limit of the host is reasonable
+	if n := cgroupCPULimit(); n != -1 && !(0 < n && n < 1<<20) {
+		t.Fatalf("expected -1 or a value between 0..%d got: %d", 1<<20, n)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package fastwalk
+
+func cgroupCPULimit() int {
+	return -1
+}
@@ -0,0 +1,259 @@
+package fastwalk
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+)
+
+// errOrderedAbort is returned up the call stack of an ordered walk once
+// an error has been recorded in orderedWalk.err.
+var errOrderedAbort = errors.New("fastwalk: ordered walk aborted")
+
+// An orderedWalk implements Config.Ordered. The file tree is walked
+// depth-first by a single goroutine, which calls the user's callback, while
+// directories are read ahead of it by a pool of prefetch workers.
+//
+// Directory listings act as the reorder buffer: when the listing of a
+// directory is consumed the listings of its sub-directories are scheduled
+// for prefetching, most recently scheduled first, so that workers read the
+// directories that the walk will visit next. The number of listings that
+// are read, or being read, but not yet consumed is bounded by limit.
+type orderedWalk struct {
+	w     *walker
+	mode  SortMode // sort mode used to read directories
+	err   error    // first error returned by the walk (see errOrderedAbort)
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	cond  sync.Cond
+	stack []*dirListing          // pending listings (LIFO)
+	dirs  map[string]*dirListing // scheduled listings by path
+	inUse int                    // listings being read or not yet consumed
+	limit int
+	done  bool // walk is complete
+}
+
+// A dirListing is the sorted contents of a directory.
+type dirListing struct {
+	path    string
+	depth   int // depth of the directory's entries
+	state   int // listingPending, listingReading or listingDone
+	dropped bool
+	ents    []DirEntry
+	err     error
+	donec   chan struct{} // closed once read
+}
+
+const (
+	listingPending = iota
+	listingReading
+	listingDone
+)
+
+// walkOrdered walks the tree rooted at it using numWorkers goroutines to
+// prefetch directory listings. The callback is invoked by the calling
+// goroutine.
+func (w *walker) walkOrdered(it walkItem, numWorkers int) error {
+	o := &orderedWalk{
+		w:     w,
+		mode:  w.sortMode,
+		dirs:  make(map[string]*dirListing),
+		limit: numWorkers * 8,
+	}
+	if o.mode == SortNone {
+		o.mode = SortLexical
+	}
+	o.cond.L = &o.mu
+	for i := 0; i < numWorkers; i++ {
+		o.wg.Add(1)
+		go o.prefetch()
+	}
+	defer func() {
+		o.mu.Lock()
+		o.done = true
+		o.cond.Broadcast()
+		o.mu.Unlock()
+		o.wg.Wait()
+	}()
+
+	w.order = o
+	if err := w.walk(it); err != nil {
+		if err == errOrderedAbort {
+			return o.err
+		}
+		return err
+	}
+	return nil
+}
+
+// walkChild walks the directory it, which is visited immediately. It
+// replaces enqueue in an ordered walk.
+func (o *orderedWalk) walkChild(it walkItem) error {
+	if err := o.w.walk(it); err != nil {
+		if err != errOrderedAbort {
+			o.err = err
+		}
+		return errOrderedAbort
+	}
+	return nil
+}
+
+// readDir is the ordered equivalent of walker.readDir.
+func (o *orderedWalk) readDir(dirName string, depth, linkDepth int) error {
+	ents, readErr := o.take(dirName, depth)
+	scheduled := o.schedule(ents, dirName)
+	defer o.drop(scheduled)
+
+	skipFiles := false
+	for _, de := range ents {
+		if skipFiles && de.Type().IsRegular() {
+			continue
+		}
+		if err := o.w.onDirEnt(dirName, de.Name(), de, linkDepth); err != nil {
+			switch err {
+			case ErrSkipFiles:
+				skipFiles = true
+			case filepath.SkipDir:
+				// Since the order is deterministic, SkipDir on a
+				// file skips the remaining entries like WalkDir.
+				return nil
+			default:
+				return err
+			}
+		}
+	}
+	return readErr
+}
+
+// schedule schedules the sub-directories in ents, which are entries in
+// the directory dirName, for prefetching and returns their paths.
+func (o *orderedWalk) schedule(ents []DirEntry, dirName string) []string {
+	w := o.w
+	var paths []string
+	o.mu.Lock()
+	// Push in reverse order so that the first directory is read first.
+	for i := len(ents) - 1; i >= 0; i-- {
+		de := ents[i]
+		if !de.IsDir() || (w.maxDepth > 0 && de.Depth() >= w.maxDepth) {
+			continue
+		}
+		path := w.joinPaths(dirName, de.Name())
+		if _, prune := w.matchPath(path); prune || o.dirs[path] != nil {
+			continue
+		}
+		l := &dirListing{
+			path:  path,
+			depth: de.Depth() + 1,
+			donec: make(chan struct{}),
+		}
+		o.dirs[path] = l
+		o.stack = append(o.stack, l)
+		paths = append(paths, path)
+	}
+	o.cond.Broadcast()
+	o.mu.Unlock()
+	return paths
+}
+
+// take returns the listing of the directory at path, reading it if it
+// has not already been read.
+func (o *orderedWalk) take(path string, depth int) ([]DirEntry, error) {
+	o.mu.Lock()
+	l := o.dirs[path]
+	if l == nil || l.state == listingPending {
+		// Not scheduled or no worker has started reading it: claim it
+		// and read it ourselves.
+		if l != nil {
+			delete(o.dirs, path)
+			l.dropped = true
+		}
+		o.mu.Unlock()
+		return o.read(path, depth)
+	}
+	o.mu.Unlock()
+
+	<-l.donec
+
+	o.mu.Lock()
+	delete(o.dirs, path)
+	l.dropped = true
+	o.inUse--
+	o.cond.Broadcast()
+	o.mu.Unlock()
+	return l.ents, l.err
+}
+
+// drop discards any listings of paths that were scheduled but not
+// consumed, which happens when a directory is skipped.
+func (o *orderedWalk) drop(paths []string) {
+	o.mu.Lock()
+	for _, path := range paths {
+		l := o.dirs[path]
+		if l == nil {
+			continue // consumed
+		}
+		delete(o.dirs, path)
+		l.dropped = true
+		if l.state == listingDone {
+			o.inUse--
+		}
+		l.ents = nil
+	}
+	o.cond.Broadcast()
+	o.mu.Unlock()
+}
+
+// read returns the sorted entries of the directory at path.
+func (o *orderedWalk) read(path string, depth int) ([]DirEntry, error) {
+	if err := o.w.throttle(path); err != nil {
+		return nil, err
+	}
+	var ents []DirEntry
+	lw := walker{
+		toSlash:   o.w.toSlash,
+		sortMode:  o.mode,
+		listing:   &ents,
+		statLimit: o.w.statLimit,
+	}
+	err := lw.readDir(path, depth, 0)
+	return ents, err
+}
+
+// prefetch reads scheduled listings until the walk is complete.
+func (o *orderedWalk) prefetch() {
+	defer o.wg.Done()
+	for {
+		o.mu.Lock()
+		for !o.done && (len(o.stack) == 0 || o.inUse >= o.limit) {
+			o.cond.Wait()
+		}
+		if o.done {
+			o.mu.Unlock()
+			return
+		}
+		l := o.stack[len(o.stack)-1]
+		o.stack[len(o.stack)-1] = nil
+		o.stack = o.stack[:len(o.stack)-1]
+		if l.dropped {
+			o.mu.Unlock()
+			continue
+		}
+		l.state = listingReading
+		o.inUse++
+		o.mu.Unlock()
+
+		ents, err := o.read(l.path, l.depth)
+
+		o.mu.Lock()
+		l.state = listingDone
+		if l.dropped {
+			o.inUse--
+			o.cond.Broadcast()
+		} else {
+			l.ents = ents
+			l.err = err
+		}
+		close(l.donec)
+		o.mu.Unlock()
+	}
+}
@@ -0,0 +1,44 @@
+package fastwalk
+
+import (
+	"io/fs"
+	"runtime/debug"
+)
+
+// A PanicError is returned by [Walk] when the RecoverPanics [Config] option
+// is true and the WalkDirFunc panics.
+type PanicError struct {
+	Path  string // Path passed to the WalkDirFunc that panicked
+	Value any    // Value passed to panic
+	Stack []byte // Stack trace of the goroutine that panicked
+}
+
+func (e *PanicError) Error() string {
+	var msg string
+	switch v := e.Value.(type) {
+	case error:
+		msg = v.Error()
+	case string:
+		msg = v
+	default:
+		msg = "non-string panic value (see PanicError.Value)"
+	}
+	return "fastwalk: panic walking " + e.Path + ": " + msg
+}
+
+// Unwrap returns the value passed to panic if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+func recoverWalkFunc(walkFn fs.WalkDirFunc) fs.WalkDirFunc {
+	return func(path string, d fs.DirEntry, err error) (rerr error) {
+		defer func() {
+			if e := recover(); e != nil {
+				rerr = &PanicError{Path: path, Value: e, Stack: debug.Stack()}
+			}
+		}()
+		return walkFn(path, d, err)
+	}
+}
@@ -0,0 +1,127 @@
+package fastwalk
+
+import (
+	"errors"
+	"io/fs"
+	"sync"
+)
+
+// ErrProcessorClosed is returned by [Processor.Submit] after
+// [Processor.Wait] has been called.
+var ErrProcessorClosed = errors.New("fastwalk: submit on closed Processor")
+
+// A Processor performs per-file work, such as reading or hashing files, on
+// its own pool of goroutines so that expensive work does not stall the
+// goroutines that Walk uses to read directories. This allows the number of
+// goroutines discovering entries (Config.NumWorkers) and the number
+// processing them to be tuned independently and limits the number of files
+// that are open concurrently.
+//
+// The queue of pending work is bounded: when all of the Processor's workers
+// are busy, Submit blocks the calling Walk goroutine, which in turn stops it
+// from reading directories and enqueuing more work. A typical use is:
+//
+//	p := fastwalk.NewProcessor(4, func(path string, d fs.DirEntry) error {
+//		return hashFile(path)
+//	})
+//	err := fastwalk.Walk(conf, root, func(path string, d fs.DirEntry, err error) error {
+//		if err == nil && d.Type().IsRegular() {
+//			return p.Submit(path, d)
+//		}
+//		return err
+//	})
+//	if perr := p.Wait(); err == nil {
+//		err = perr
+//	}
+type Processor struct {
+	fn    func(path string, d fs.DirEntry) error
+	workc chan processItem
+	wg    sync.WaitGroup
+
+	mu     sync.Mutex
+	err    error // first error returned by fn
+	closed bool
+}
+
+type processItem struct {
+	path string
+	d    fs.DirEntry
+}
+
+// NewProcessor returns a new Processor that calls fn for each submitted entry
+// using numWorkers goroutines. If numWorkers is ≤ 0 then [DefaultNumWorkers]
+// is used. The function fn must be safe for concurrent use.
+func NewProcessor(numWorkers int, fn func(path string, d fs.DirEntry) error) *Processor {
+	if numWorkers <= 0 {
+		numWorkers = DefaultNumWorkers()
+	}
+	p := &Processor{
+		fn:    fn,
+		workc: make(chan processItem, numWorkers),
+	}
+	p.wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *Processor) work() {
+	defer p.wg.Done()
+	for it := range p.workc {
+		// Drain, but do not process, any remaining work once an
+		// error has occurred.
+		if p.Err() != nil {
+			continue
+		}
+		if err := p.fn(it.path, it.d); err != nil {
+			p.mu.Lock()
+			if p.err == nil {
+				p.err = err
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+// Err returns the first error returned by the Processor's function, if any.
+func (p *Processor) Err() error {
+	p.mu.Lock()
+	err := p.err
+	p.mu.Unlock()
+	return err
+}
+
+// Submit queues path and d to be processed. It blocks if the queue is full.
+// If an earlier call to the Processor's function returned an error, Submit
+// returns that error so that it can be returned from the WalkDirFunc to stop
+// the walk. Submit must not be called concurrently with Wait and returns
+// ErrProcessorClosed if it is called after Wait.
+func (p *Processor) Submit(path string, d fs.DirEntry) error {
+	p.mu.Lock()
+	err := p.err
+	if p.closed {
+		err = ErrProcessorClosed
+	}
+	p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	p.workc <- processItem{path: path, d: d}
+	return nil
+}
+
+// Wait waits for all submitted work to complete, stops the Processor's
+// goroutines, and returns the first error returned by the Processor's
+// function. Wait should be called once Walk returns.
+func (p *Processor) Wait() error {
+	p.mu.Lock()
+	closed := p.closed
+	p.closed = true
+	p.mu.Unlock()
+	if !closed {
+		close(p.workc)
+	}
+	p.wg.Wait()
+	return p.Err()
+}
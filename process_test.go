@@ -0,0 +1,92 @@
+package fastwalk_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/charlievieth/fastwalk"
+)
+
+func TestProcessor(t *testing.T) {
+	tempdir := t.TempDir()
+	const numFiles = 64
+	for i := 0; i < numFiles; i++ {
+		name := filepath.Join(tempdir, fmt.Sprintf("d%d/f%02d.txt", i%4, i))
+		if err := writeFile(name, "data", 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const numProcs = 2
+	var running, maxRunning, processed atomic.Int32
+	p := fastwalk.NewProcessor(numProcs, func(path string, d fs.DirEntry) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		if _, err := os.ReadFile(path); err != nil {
+			return err
+		}
+		time.Sleep(time.Millisecond)
+		processed.Add(1)
+		return nil
+	})
+	err := fastwalk.Walk(nil, tempdir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			return p.Submit(path, d)
+		}
+		return err
+	})
+	if perr := p.Wait(); err == nil {
+		err = perr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := processed.Load(); n != numFiles {
+		t.Errorf("processed %d files want: %d", n, numFiles)
+	}
+	if n := maxRunning.Load(); n > numProcs {
+		t.Errorf("ran %d concurrent jobs want at most: %d", n, numProcs)
+	}
+	if err := p.Submit("", nil); err != fastwalk.ErrProcessorClosed {
+		t.Errorf("Submit after Wait: got: %v want: %v", err, fastwalk.ErrProcessorClosed)
+	}
+}
+
+func TestProcessorError(t *testing.T) {
+	tempdir := t.TempDir()
+	for i := 0; i < 64; i++ {
+		name := filepath.Join(tempdir, fmt.Sprintf("f%02d.txt", i))
+		if err := writeFile(name, "data", 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := errors.New("process error")
+	p := fastwalk.NewProcessor(1, func(path string, d fs.DirEntry) error {
+		return want
+	})
+	err := fastwalk.Walk(nil, tempdir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			return p.Submit(path, d)
+		}
+		return err
+	})
+	if err != nil && err != want {
+		t.Errorf("Walk: got: %v want: %v", err, want)
+	}
+	if err := p.Wait(); err != want {
+		t.Errorf("Wait: got: %v want: %v", err, want)
+	}
+}
@@ -0,0 +1,90 @@
+package fastwalk
+
+import "sync"
+
+// Reduce walks the file tree rooted at root, calling mapFn for each file or
+// directory in the tree, including root, and combines the returned values
+// using merge.
+//
+// Each of the goroutines used by the walk accumulates its own partial
+// result so no locking is required by mapFn or merge. The partial results
+// are combined once the walk completes. Since the order in which entries
+// are visited, and the worker that visits them, is not deterministic merge
+// should be associative and commutative. Merge is never called with a value
+// that was not returned by mapFn or merge.
+//
+// If mapFn returns an error the value it returned is discarded. The errors
+// [filepath.SkipDir] and [ErrTraverseLink] are handled the same as they are
+// by [Walk] and any other error stops the walk. Errors encountered while
+// reading directories also stop the walk. If the walk fails the zero value
+// of T and the error are returned.
+//
+// For example, to count the number of files in a directory tree by their
+// extension:
+//
+//	counts, err := fastwalk.Reduce(conf, root,
+//		func(path string, d fastwalk.DirEntry) (map[string]int, error) {
+//			if !d.Type().IsRegular() {
+//				return nil, nil
+//			}
+//			return map[string]int{filepath.Ext(path): 1}, nil
+//		},
+//		func(a, b map[string]int) map[string]int {
+//			if a == nil {
+//				return b
+//			}
+//			for ext, n := range b {
+//				a[ext] += n
+//			}
+//			return a
+//		})
+func Reduce[T any](conf *Config, root string, mapFn func(path string, d DirEntry) (T, error),
+	merge func(a, b T) T) (T, error) {
+
+	var (
+		mu     sync.Mutex // only used when creating worker state
+		states []*reduceState[T]
+	)
+	newState := func() *reduceState[T] {
+		st := new(reduceState[T])
+		mu.Lock()
+		states = append(states, st)
+		mu.Unlock()
+		return st
+	}
+	err := WalkWithState(conf, root, newState, func(st *reduceState[T], path string, d DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		v, err := mapFn(path, d)
+		if err != nil {
+			return err
+		}
+		st.add(v, merge)
+		return nil
+	})
+	var res reduceState[T]
+	if err != nil {
+		return res.acc, err
+	}
+	for _, st := range states {
+		if st.ok {
+			res.add(st.acc, merge)
+		}
+	}
+	return res.acc, nil
+}
+
+type reduceState[T any] struct {
+	acc T
+	ok  bool // acc is set
+}
+
+func (s *reduceState[T]) add(v T, merge func(a, b T) T) {
+	if s.ok {
+		s.acc = merge(s.acc, v)
+	} else {
+		s.acc = v
+		s.ok = true
+	}
+}
@@ -0,0 +1,119 @@
+package fastwalk_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/charlievieth/fastwalk"
+)
+
+func TestReduce(t *testing.T) {
+	tempdir := t.TempDir()
+	want := make(map[string]int)
+	for i := 0; i < 90; i++ {
+		ext := []string{".go", ".txt", ".md"}[i%3]
+		name := filepath.Join(tempdir, fmt.Sprintf("d%d/f%02d%s", i%5, i, ext))
+		if err := writeFile(name, "data", 0644); err != nil {
+			t.Fatal(err)
+		}
+		want[ext]++
+	}
+
+	conf := fastwalk.Config{NumWorkers: 4}
+	got, err := fastwalk.Reduce(&conf, tempdir,
+		func(path string, d fastwalk.DirEntry) (map[string]int, error) {
+			if !d.Type().IsRegular() {
+				return nil, nil
+			}
+			return map[string]int{filepath.Ext(path): 1}, nil
+		},
+		func(a, b map[string]int) map[string]int {
+			if a == nil {
+				return b
+			}
+			for k, n := range b {
+				a[k] += n
+			}
+			return a
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v want: %v", got, want)
+	}
+}
+
+func TestReduce_SkipDir(t *testing.T) {
+	tempdir := t.TempDir()
+	testCreateFiles(t, tempdir, map[string]string{
+		"a/f1":      "1",
+		"a/f2":      "22",
+		"skip/f3":   "333",
+		"skip/b/f4": "4444",
+	})
+	got, err := fastwalk.Reduce(nil, tempdir,
+		func(path string, d fastwalk.DirEntry) (int64, error) {
+			if d.IsDir() && d.Name() == "skip" {
+				// The returned value is discarded.
+				return 1000, filepath.SkipDir
+			}
+			if !d.Type().IsRegular() {
+				return 0, nil
+			}
+			fi, err := d.Info()
+			if err != nil {
+				return 0, err
+			}
+			return fi.Size(), nil
+		},
+		func(a, b int64) int64 { return a + b })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 3 {
+		t.Errorf("got: %d want: %d", got, 3)
+	}
+}
+
+func TestReduce_Error(t *testing.T) {
+	tempdir := t.TempDir()
+	testCreateFiles(t, tempdir, map[string]string{
+		"a/b/c": "data",
+	})
+	want := errors.New("expected")
+	got, err := fastwalk.Reduce(nil, tempdir,
+		func(path string, d fastwalk.DirEntry) (int, error) {
+			if d.Name() == "c" {
+				return 1, want
+			}
+			return 1, nil
+		},
+		func(a, b int) int { return a + b })
+	if err != want {
+		t.Errorf("got error: %v want: %v", err, want)
+	}
+	if got != 0 {
+		t.Errorf("got: %d want: %d", got, 0)
+	}
+}
+
+func TestReduce_Empty(t *testing.T) {
+	got, err := fastwalk.Reduce(nil, t.TempDir(),
+		func(path string, d fastwalk.DirEntry) (int, error) {
+			return 0, filepath.SkipDir
+		},
+		func(a, b int) int {
+			t.Error("merge should not be called")
+			return a + b
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 0 {
+		t.Errorf("got: %d want: %d", got, 0)
+	}
+}
@@ -0,0 +1,288 @@
+package fastwalk
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// A scheduler distributes directories among the Walk workers using work
+// stealing. Each worker has its own deque: directories found by a worker
+// are pushed to the back of its own deque and idle workers steal from the
+// front of other workers' deques, which holds the directories closest to
+// the root and thus likely the largest amount of work.
+//
+// The Strategy determines which end of its own deque a worker pops from:
+// the back for DepthFirst, the front for BreadthFirst and, for Hybrid, the
+// front unless the number of pending directories has reached limit.
+//
+// The walk is complete when there are no pending directories, that is
+// directories that have been pushed but not yet walked.
+type scheduler struct {
+	queues   []*workQueue
+	strategy Strategy
+	limit    int64        // Hybrid queue limit
+	pending  atomic.Int64 // directories pushed but not walked
+	idle     atomic.Int32 // number of workers waiting for work
+	stopped  atomic.Bool  // fast path for checking done
+	active   atomic.Int32 // workers with an index ≥ active are parked (Config.AutoTune)
+
+	// Config.MaxQueuedDirs
+	spill     *spillQueue  // nil if disabled
+	maxQueued int64        // maximum number of items in queues
+	queued    atomic.Int64 // number of items in queues
+
+	// ckpt is held for reading by each worker from when it takes an item
+	// until it has finished walking it, which allows snapshot to pause the
+	// walk at a consistent point. It is nil unless checkpointing.
+	ckpt *sync.RWMutex
+
+	mu   sync.Mutex
+	cond sync.Cond // signaled when work is pushed or the walk stops
+	park sync.Cond // signaled when active changes or the walk stops
+	done bool
+	err  error // first error
+}
+
+// A workQueue is a worker's deque.
+type workQueue struct {
+	mu    sync.Mutex
+	items []walkItem
+	_     [64]byte // avoid false sharing between queues
+}
+
+func newScheduler(numWorkers int, strategy Strategy) *scheduler {
+	s := &scheduler{
+		queues:   make([]*workQueue, numWorkers),
+		strategy: strategy,
+		limit:    int64(numWorkers) * hybridQueueLimit,
+	}
+	for i := range s.queues {
+		s.queues[i] = new(workQueue)
+	}
+	s.active.Store(int32(numWorkers))
+	s.cond.L = &s.mu
+	s.park.L = &s.mu
+	return s
+}
+
+// push adds it to the back of the queue of worker i.
+func (s *scheduler) push(i int, it walkItem) {
+	q := s.queues[i]
+	s.pending.Add(1)
+	// Batches of entries are not spilled since they are bounded by the
+	// number of workers.
+	if s.spill != nil && it.batch == nil && s.queued.Load() >= s.maxQueued {
+		if err := s.spill.Push(it); err != nil {
+			s.stop(err)
+		}
+		return
+	}
+	s.queued.Add(1)
+	q.mu.Lock()
+	q.items = append(q.items, it)
+	q.mu.Unlock()
+	if s.idle.Load() > 0 {
+		s.mu.Lock()
+		s.cond.Signal()
+		s.mu.Unlock()
+	}
+}
+
+// next returns the next directory for worker i to walk. It blocks until
+// work is available and returns false once the walk is complete or stopped.
+func (s *scheduler) next(i int) (walkItem, bool) {
+	for {
+		if s.stopped.Load() {
+			return walkItem{}, false
+		}
+		if int32(i) >= s.active.Load() {
+			s.parkWorker(i)
+			continue
+		}
+		if s.ckpt != nil {
+			s.ckpt.RLock() // released by finish
+		}
+		if it, ok := s.find(i); ok {
+			return it, true
+		}
+		if s.spill != nil {
+			it, ok, err := s.unspill(i)
+			if err != nil {
+				s.unlockCheckpoint()
+				s.stop(err)
+				return walkItem{}, false
+			}
+			if ok {
+				return it, true
+			}
+		}
+		s.unlockCheckpoint()
+		if it, ok, done := s.wait(i); ok || done {
+			return it, ok
+		}
+	}
+}
+
+// wait waits for work to be pushed. If neither ok nor done are true then
+// there are directories to take (when checkpointing), spilled directories
+// to read or worker i must park.
+func (s *scheduler) wait(i int) (it walkItem, ok, done bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for !s.done && int32(i) < s.active.Load() {
+		// Increment idle before checking the queues so that a concurrent
+		// push either is seen here or signals us.
+		s.idle.Add(1)
+		if s.ckpt != nil {
+			// Items may only be taken while holding ckpt, which
+			// cannot be acquired here since a snapshot waits for
+			// workers that may be blocked on mu in push.
+			if s.queued.Load() > 0 || (s.spill != nil && s.spill.Len() > 0) {
+				s.idle.Add(-1)
+				return it, false, false
+			}
+		} else if it, ok = s.find(i); ok || (s.spill != nil && s.spill.Len() > 0) {
+			s.idle.Add(-1)
+			return it, ok, false
+		}
+		s.cond.Wait()
+		s.idle.Add(-1)
+	}
+	return it, false, s.done
+}
+
+// parkWorker blocks worker i until it is made active or the walk stops.
+// Parked workers do not take work, but work already in their queue can
+// be stolen by the active workers.
+func (s *scheduler) parkWorker(i int) {
+	s.mu.Lock()
+	for !s.done && int32(i) >= s.active.Load() {
+		s.park.Wait()
+	}
+	s.mu.Unlock()
+}
+
+// setActive sets the number of workers that take work to n.
+func (s *scheduler) setActive(n int) {
+	s.mu.Lock()
+	s.active.Store(int32(n))
+	s.park.Broadcast()
+	s.cond.Broadcast() // wake workers that are now parked
+	s.mu.Unlock()
+}
+
+// unspill moves spilled directories to the queue of worker i and returns
+// the first one.
+func (s *scheduler) unspill(i int) (walkItem, bool, error) {
+	max := int(s.maxQueued / 2)
+	if max < 1 {
+		max = 1
+	}
+	items, err := s.spill.Pop(max)
+	if err != nil || len(items) == 0 {
+		return walkItem{}, false, err
+	}
+	if len(items) > 1 {
+		q := s.queues[i]
+		s.queued.Add(int64(len(items) - 1))
+		q.mu.Lock()
+		q.items = append(q.items, items[1:]...)
+		q.mu.Unlock()
+		if s.idle.Load() > 0 {
+			s.mu.Lock()
+			s.cond.Broadcast()
+			s.mu.Unlock()
+		}
+	}
+	return items[0], true, nil
+}
+
+// find pops an item from the queue of worker i or, if it is empty, steals
+// one from another worker.
+func (s *scheduler) find(i int) (walkItem, bool) {
+	var it walkItem
+	var ok bool
+	switch s.strategy {
+	case BreadthFirst:
+		it, ok = s.queues[i].popFront()
+	case Hybrid:
+		if s.pending.Load() < s.limit {
+			it, ok = s.queues[i].popFront()
+		} else {
+			it, ok = s.queues[i].popBack()
+		}
+	default:
+		it, ok = s.queues[i].popBack()
+	}
+	if !ok {
+		// Start with the queue after ours so that thieves are spread out.
+		n := len(s.queues)
+		for j := 1; j < n && !ok; j++ {
+			it, ok = s.queues[(i+j)%n].popFront()
+		}
+	}
+	if ok {
+		s.queued.Add(-1)
+	}
+	return it, ok
+}
+
+// finish is called after a directory has been walked. It stops the walk if
+// err is non-nil or there is no more work.
+func (s *scheduler) finish(err error) {
+	if err != nil {
+		// Stop before releasing ckpt so that a snapshot does not miss
+		// the item that failed.
+		s.stop(err)
+		s.unlockCheckpoint()
+		return
+	}
+	s.unlockCheckpoint()
+	if s.pending.Add(-1) == 0 {
+		s.stop(nil)
+	}
+}
+
+func (s *scheduler) unlockCheckpoint() {
+	if s.ckpt != nil {
+		s.ckpt.RUnlock()
+	}
+}
+
+// stop stops the walk. Only the first error is recorded.
+func (s *scheduler) stop(err error) {
+	s.mu.Lock()
+	if !s.done {
+		s.done = true
+		s.err = err
+		s.stopped.Store(true)
+		s.cond.Broadcast()
+		s.park.Broadcast()
+	}
+	s.mu.Unlock()
+}
+
+func (q *workQueue) popBack() (walkItem, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := len(q.items) - 1
+	if n < 0 {
+		return walkItem{}, false
+	}
+	it := q.items[n]
+	q.items[n] = walkItem{} // release memory
+	q.items = q.items[:n]
+	return it, true
+}
+
+func (q *workQueue) popFront() (walkItem, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		return walkItem{}, false
+	}
+	it := q.items[0]
+	q.items[0] = walkItem{} // release memory
+	q.items = q.items[1:]
+	return it, true
+}
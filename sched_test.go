@@ -0,0 +1,98 @@
+package fastwalk
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestSchedulerOrder(t *testing.T) {
+	s := newScheduler(2, DepthFirst)
+	for _, dir := range []string{"a", "b", "c"} {
+		s.push(0, walkItem{dir: dir})
+	}
+	// The owner pops from the back.
+	if it, ok := s.next(0); !ok || it.dir != "c" {
+		t.Errorf("next(0) = %q, %t; want: %q, true", it.dir, ok, "c")
+	}
+	// Thieves steal from the front.
+	if it, ok := s.next(1); !ok || it.dir != "a" {
+		t.Errorf("next(1) = %q, %t; want: %q, true", it.dir, ok, "a")
+	}
+	for i := 0; i < 3; i++ {
+		s.finish(nil)
+	}
+	if _, ok := s.next(0); ok {
+		t.Error("next should return false once there are no pending items")
+	}
+	if s.err != nil {
+		t.Errorf("err = %v; want: nil", s.err)
+	}
+}
+
+func TestSchedulerStrategy(t *testing.T) {
+	for _, test := range []struct {
+		strategy Strategy
+		limit    int64
+		want     string
+	}{
+		{DepthFirst, 0, "dcba"},
+		{BreadthFirst, 0, "abcd"},
+		{Hybrid, 100, "abcd"},
+		// Depth-first until the number of pending items drops below 3.
+		{Hybrid, 3, "dcab"},
+	} {
+		s := newScheduler(1, test.strategy)
+		if test.limit != 0 {
+			s.limit = test.limit
+		}
+		for _, dir := range []string{"a", "b", "c", "d"} {
+			s.push(0, walkItem{dir: dir})
+		}
+		var order string
+		for {
+			it, ok := s.next(0)
+			if !ok {
+				break
+			}
+			order += it.dir
+			s.finish(nil)
+		}
+		if order != test.want {
+			t.Errorf("%s(limit=%d): got order %q want: %q", test.strategy, test.limit, order, test.want)
+		}
+	}
+}
+
+func TestSchedulerError(t *testing.T) {
+	const numWorkers = 8
+	s := newScheduler(numWorkers, DepthFirst)
+	s.push(0, walkItem{dir: "root"})
+
+	errStop := errors.New("stop")
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				it, ok := s.next(i)
+				if !ok {
+					return
+				}
+				var err error
+				if len(it.dir) < 6 {
+					s.push(i, walkItem{dir: it.dir + "/a"})
+					s.push(i, walkItem{dir: it.dir + "/b"})
+				} else {
+					err = errStop
+				}
+				s.finish(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if s.err != errStop {
+		t.Errorf("err = %v; want: %v", s.err, errStop)
+	}
+}
@@ -0,0 +1,234 @@
+package fastwalk
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// A spillQueue stores directories that are waiting to be walked in a
+// temporary file when the number of queued directories exceeds
+// Config.MaxQueuedDirs. Directories are read back in the order they were
+// written.
+//
+// Each directory is stored as a record containing the information needed
+// to recreate its walkItem:
+//
+//	flags     byte   (spillCallbackDone, spillSymlink)
+//	depth     uint64
+//	linkDepth uint64
+//	len(dir)  uint64
+//	len(name) uint64
+//	dir       []byte
+//	name      []byte
+type spillQueue struct {
+	mu    sync.Mutex
+	f     *os.File
+	wbuf  []byte // records not yet written to f
+	rbuf  []byte
+	woff  int64 // offset of the end of the file
+	roff  int64 // offset of the next record to read
+	n     int   // number of records in the file and wbuf
+	err   error
+	limit *rateLimiter // Config.MaxStatsPerSecond
+}
+
+const (
+	spillCallbackDone = 1 << iota
+	spillSymlink
+
+	spillHeaderSize = 1 + 4*8
+	spillBufferSize = 64 * 1024
+)
+
+var errSpillCorrupt = errors.New("fastwalk: corrupt spill file")
+
+func newSpillQueue(limit *rateLimiter) (*spillQueue, error) {
+	f, err := os.CreateTemp("", "fastwalk-spill-*")
+	if err != nil {
+		return nil, err
+	}
+	return &spillQueue{f: f, limit: limit}, nil
+}
+
+// Close closes and removes the spill file.
+func (q *spillQueue) Close() error {
+	err := q.f.Close()
+	if rerr := os.Remove(q.f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// Len returns the number of spilled directories.
+func (q *spillQueue) Len() int {
+	q.mu.Lock()
+	n := q.n
+	q.mu.Unlock()
+	return n
+}
+
+// Push spills it.
+func (q *spillQueue) Push(it walkItem) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.err != nil {
+		return q.err
+	}
+	q.wbuf = appendSpillRecord(q.wbuf, it)
+	q.n++
+	if len(q.wbuf) >= spillBufferSize {
+		return q.flush()
+	}
+	return nil
+}
+
+func (q *spillQueue) flush() error {
+	if len(q.wbuf) == 0 {
+		return nil
+	}
+	if _, err := q.f.WriteAt(q.wbuf, q.woff); err != nil {
+		q.err = err
+		return err
+	}
+	q.woff += int64(len(q.wbuf))
+	q.wbuf = q.wbuf[:0]
+	return nil
+}
+
+// Pop returns up to max spilled directories.
+func (q *spillQueue) Pop(max int) ([]walkItem, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.err != nil || q.n == 0 {
+		return nil, q.err
+	}
+	if err := q.flush(); err != nil {
+		return nil, err
+	}
+	var items []walkItem
+	for len(items) < max && q.roff < q.woff {
+		size := spillBufferSize
+		if cap(q.rbuf) > size {
+			size = cap(q.rbuf) // grown to fit a large record
+		}
+		if rem := q.woff - q.roff; rem < int64(size) {
+			size = int(rem)
+		}
+		if cap(q.rbuf) < size {
+			q.rbuf = make([]byte, size)
+		}
+		buf := q.rbuf[:size]
+		if _, err := q.f.ReadAt(buf, q.roff); err != nil && err != io.EOF {
+			q.err = err
+			return nil, err
+		}
+		consumed := 0
+		for len(items) < max {
+			it, n := parseSpillRecord(buf[consumed:], q.limit)
+			if n == 0 {
+				break
+			}
+			items = append(items, it)
+			consumed += n
+		}
+		if consumed == 0 {
+			// The next record is larger than buf.
+			if len(buf) < spillHeaderSize {
+				q.err = errSpillCorrupt
+				return nil, q.err
+			}
+			need := spillHeaderSize + int(readUint64(buf[17:])+readUint64(buf[25:]))
+			if need <= len(buf) || int64(need) > q.woff-q.roff {
+				q.err = errSpillCorrupt
+				return nil, q.err
+			}
+			q.rbuf = make([]byte, need)
+			continue
+		}
+		q.roff += int64(consumed)
+	}
+	q.n -= len(items)
+	if q.n == 0 {
+		// Reuse the file.
+		q.roff = 0
+		q.woff = 0
+		if err := q.f.Truncate(0); err != nil {
+			q.err = err
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// WriteTo writes the records of the spilled directories to w.
+func (q *spillQueue) WriteTo(w io.Writer) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.err != nil {
+		return 0, q.err
+	}
+	n, err := io.Copy(w, io.NewSectionReader(q.f, q.roff, q.woff-q.roff))
+	if err != nil {
+		return n, err
+	}
+	nw, err := w.Write(q.wbuf)
+	return n + int64(nw), err
+}
+
+// appendSpillRecord appends the record for it to b.
+func appendSpillRecord(b []byte, it walkItem) []byte {
+	var flags byte
+	if it.callbackDone {
+		flags |= spillCallbackDone
+	}
+	if it.info.Type()&os.ModeSymlink != 0 {
+		flags |= spillSymlink
+	}
+	name := it.info.Name()
+	b = append(b, flags)
+	b = appendUint64(b, uint64(it.info.Depth()))
+	b = appendUint64(b, uint64(it.linkDepth))
+	b = appendUint64(b, uint64(len(it.dir)))
+	b = appendUint64(b, uint64(len(name)))
+	b = append(b, it.dir...)
+	return append(b, name...)
+}
+
+// parseSpillRecord parses the record at the start of b and returns the
+// number of bytes consumed, which is zero if b does not contain an entire
+// record. The stat calls made by the DirEntry of the item are limited by
+// limit.
+func parseSpillRecord(b []byte, limit *rateLimiter) (walkItem, int) {
+	if len(b) < spillHeaderSize {
+		return walkItem{}, 0
+	}
+	flags := b[0]
+	depth := int(readUint64(b[1:]))
+	linkDepth := int(readUint64(b[9:]))
+	ndir := readUint64(b[17:])
+	nname := readUint64(b[25:])
+	n := spillHeaderSize + ndir + nname
+	if uint64(len(b)) < n || nname > ndir {
+		return walkItem{}, 0
+	}
+	dir := string(b[spillHeaderSize : spillHeaderSize+ndir])
+	name := string(b[spillHeaderSize+ndir : n])
+	typ := os.ModeDir
+	if flags&spillSymlink != 0 {
+		typ = os.ModeSymlink
+	}
+	// The parent directory is dir without the trailing separator and name,
+	// which is what DirEntries join with name to create paths.
+	parent := ""
+	if i := len(dir) - len(name) - 1; i > 0 {
+		parent = dir[:i]
+	}
+	return walkItem{
+		dir:          dir,
+		info:         newDirEntryType(parent, name, typ, depth, limit),
+		linkDepth:    linkDepth,
+		callbackDone: flags&spillCallbackDone != 0,
+	}, int(n)
+}
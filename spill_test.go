@@ -0,0 +1,108 @@
+package fastwalk
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSpillQueue(t *testing.T) {
+	q, err := newSpillQueue(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	parent := filepath.Join(t.TempDir(), "parent")
+	long := strings.Repeat("x", spillBufferSize) // larger than the read buffer
+	var want []walkItem
+	for i := 0; i < 1000; i++ {
+		name := "d" + strconv.Itoa(i)
+		if i == 500 {
+			name = long
+		}
+		typ := os.ModeDir
+		if i%3 == 0 {
+			typ = os.ModeSymlink
+		}
+		it := walkItem{
+			dir:          parent + string(os.PathSeparator) + name,
+			info:         newDirEntryType(parent, name, typ, i%7+1, nil),
+			linkDepth:    i % 5,
+			callbackDone: i%2 == 0,
+		}
+		if err := q.Push(it); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, it)
+	}
+	if n := q.Len(); n != len(want) {
+		t.Fatalf("Len() = %d; want: %d", n, len(want))
+	}
+
+	var got []walkItem
+	for q.Len() > 0 {
+		items, err := q.Pop(64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) == 0 || len(items) > 64 {
+			t.Fatalf("Pop(64) returned %d items", len(items))
+		}
+		got = append(got, items...)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d items; want: %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.dir != w.dir || g.linkDepth != w.linkDepth || g.callbackDone != w.callbackDone ||
+			g.info.Name() != w.info.Name() || g.info.Type() != w.info.Type() ||
+			g.info.Depth() != w.info.Depth() {
+			t.Fatalf("%d: got: {%.32q %s %d %d %t} want: {%.32q %s %d %d %t}", i,
+				g.dir, g.info.Type(), g.info.Depth(), g.linkDepth, g.callbackDone,
+				w.dir, w.info.Type(), w.info.Depth(), w.linkDepth, w.callbackDone)
+		}
+	}
+
+	// The file is truncated once empty.
+	if fi, err := q.f.Stat(); err != nil {
+		t.Fatal(err)
+	} else if fi.Size() != 0 {
+		t.Errorf("spill file size = %d; want: 0", fi.Size())
+	}
+}
+
+func TestSpillQueueDirEntry(t *testing.T) {
+	tempdir := t.TempDir()
+	dir := filepath.Join(tempdir, "dir")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	q, err := newSpillQueue(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	if err := q.Push(walkItem{dir: dir, info: newDirEntryType(tempdir, "dir", os.ModeDir, 1, nil)}); err != nil {
+		t.Fatal(err)
+	}
+	items, err := q.Pop(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := items[0].info.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.Lstat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(fi, want) {
+		t.Errorf("Info() returned the wrong file: %s", fi.Name())
+	}
+}
@@ -0,0 +1,97 @@
+package fastwalk
+
+import (
+	"errors"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+)
+
+// errWalkStopped is returned internally once Walker.Stop has been called.
+var errWalkStopped = errors.New("fastwalk: walk stopped")
+
+// A Walker is a handle to a walk started by [Start] that can be used to
+// pause, resume or stop it.
+type Walker struct {
+	mu      sync.Mutex
+	cond    sync.Cond // signaled when the walk is resumed or stopped
+	paused  atomic.Bool
+	stopped atomic.Bool
+	done    chan struct{}
+	err     error
+}
+
+// Start starts walking the file tree rooted at root in the background, like
+// [Walk], and returns a Walker that can be used to control the walk.
+//
+// Paused workers block before they read their next directory, so callbacks
+// for entries of directories that were already being read may still be
+// made after Pause returns.
+func Start(conf *Config, root string, walkFn fs.WalkDirFunc) *Walker {
+	wk := &Walker{done: make(chan struct{})}
+	wk.cond.L = &wk.mu
+	fn := func(path string, d fs.DirEntry, err error) error {
+		if wk.stopped.Load() {
+			return errWalkStopped
+		}
+		return walkFn(path, d, err)
+	}
+	go func() {
+		defer close(wk.done)
+		err := walkTree(conf, root, fn, &walkOptions{handle: wk})
+		if err == errWalkStopped {
+			err = nil
+		}
+		wk.err = err
+	}()
+	return wk
+}
+
+// Pause pauses the walk. It does not wait for the workers to finish reading
+// the directories they are currently reading.
+func (wk *Walker) Pause() {
+	wk.mu.Lock()
+	wk.paused.Store(true)
+	wk.mu.Unlock()
+}
+
+// Resume resumes a paused walk.
+func (wk *Walker) Resume() {
+	wk.mu.Lock()
+	wk.paused.Store(false)
+	wk.cond.Broadcast()
+	wk.mu.Unlock()
+}
+
+// Stop stops the walk, including a paused walk. Once Stop returns no more
+// calls to walkFn are started, but calls already in progress may complete.
+// Stop does not wait for the walk to exit, use Wait for that.
+func (wk *Walker) Stop() {
+	wk.mu.Lock()
+	wk.stopped.Store(true)
+	wk.cond.Broadcast()
+	wk.mu.Unlock()
+}
+
+// Wait waits for the walk to complete and returns its error, which is nil
+// if the walk was stopped with Stop.
+func (wk *Walker) Wait() error {
+	<-wk.done
+	return wk.err
+}
+
+// wait is called by the workers before reading a directory and blocks while
+// the walk is paused. It returns errWalkStopped if the walk was stopped.
+func (wk *Walker) wait() error {
+	if wk.paused.Load() {
+		wk.mu.Lock()
+		for wk.paused.Load() && !wk.stopped.Load() {
+			wk.cond.Wait()
+		}
+		wk.mu.Unlock()
+	}
+	if wk.stopped.Load() {
+		return errWalkStopped
+	}
+	return nil
+}
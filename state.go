@@ -0,0 +1,38 @@
+package fastwalk
+
+import "io/fs"
+
+// WalkWithState is like [Walk] but each of the goroutines that Walk uses to
+// read directories is given its own state, created by calling newState once
+// when the goroutine starts. The state is passed to every invocation of fn
+// made by that goroutine, and since a goroutine makes at most one call to fn
+// at a time, fn may use the state without any synchronization.
+//
+// This is useful for reusing expensive per-call resources, such as buffers
+// or hashers, without the contention of a shared [sync.Pool] or mutex:
+//
+//	err := fastwalk.WalkWithState(conf, root,
+//		func() []byte { return make([]byte, 32*1024) },
+//		func(buf []byte, path string, d fastwalk.DirEntry, err error) error {
+//			// use buf to read the file at path
+//			return err
+//		})
+//
+// The number of states created is at most the number of workers
+// (Config.NumWorkers) and states are not shared between concurrent calls
+// to fn. Any state that needs to outlive the walk, such as a partial result,
+// must be recorded by newState or fn.
+//
+// The DirEntry passed to fn is never nil.
+func WalkWithState[S any](conf *Config, root string, newState func() S,
+	fn func(state S, path string, d DirEntry, err error) error) error {
+
+	return walkTree(conf, root, nil, &walkOptions{
+		newWalkFn: func() fs.WalkDirFunc {
+			state := newState()
+			return func(path string, d fs.DirEntry, err error) error {
+				return fn(state, path, d.(DirEntry), err)
+			}
+		},
+	})
+}
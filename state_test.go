@@ -0,0 +1,94 @@
+package fastwalk_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/charlievieth/fastwalk"
+)
+
+func TestWalkWithState(t *testing.T) {
+	tempdir := t.TempDir()
+	var want []string
+	for i := 0; i < 64; i++ {
+		name := filepath.Join(tempdir, fmt.Sprintf("d%d/f%02d.txt", i%8, i))
+		if err := writeFile(name, "data", 0644); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, name)
+	}
+
+	type workerState struct {
+		inUse atomic.Bool
+		files []string
+	}
+	var (
+		mu     sync.Mutex
+		states []*workerState
+	)
+	newState := func() *workerState {
+		st := new(workerState)
+		mu.Lock()
+		states = append(states, st)
+		mu.Unlock()
+		return st
+	}
+
+	conf := fastwalk.Config{NumWorkers: 4}
+	err := fastwalk.WalkWithState(&conf, tempdir, newState,
+		func(st *workerState, path string, d fastwalk.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d == nil {
+				t.Errorf("%s: nil DirEntry", path)
+			}
+			if !st.inUse.CompareAndSwap(false, true) {
+				t.Errorf("%s: state used concurrently", path)
+			}
+			defer st.inUse.Store(false)
+			if d.Type().IsRegular() {
+				st.files = append(st.files, path)
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(states) == 0 || len(states) > conf.NumWorkers {
+		t.Errorf("created %d states want between 1 and %d", len(states), conf.NumWorkers)
+	}
+	var got []string
+	for _, st := range states {
+		got = append(got, st.files...)
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("files:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestWalkWithState_Error(t *testing.T) {
+	tempdir := t.TempDir()
+	if err := writeFile(filepath.Join(tempdir, "a/b/c.txt"), "data", 0644); err != nil {
+		t.Fatal(err)
+	}
+	want := errors.New("expected")
+	err := fastwalk.WalkWithState(nil, tempdir, func() int { return 0 },
+		func(_ int, path string, d fastwalk.DirEntry, err error) error {
+			if d.Name() == "c.txt" {
+				return want
+			}
+			return err
+		})
+	if err != want {
+		t.Fatalf("got error: %v want: %v", err, want)
+	}
+}
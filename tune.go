@@ -0,0 +1,162 @@
+package fastwalk
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// autoTuneMinWorkers is the number of workers an auto-tuned walk
+	// starts with.
+	autoTuneMinWorkers = 2
+
+	// autoTuneInterval is how often the tuner measures throughput.
+	autoTuneInterval = 50 * time.Millisecond
+
+	// autoTuneMinDirs is the minimum number of directories that must be
+	// read for a measurement to be used. Samples with fewer directories are
+	// merged with the next one.
+	autoTuneMinDirs = 16
+
+	// autoTuneGain is the relative increase in throughput required to
+	// consider adding workers to be an improvement.
+	autoTuneGain = 0.10
+
+	// autoTuneSlowdown is the factor by which the time to read a directory
+	// must increase, compared to when the best throughput was measured,
+	// before the tuner removes workers.
+	autoTuneSlowdown = 2
+)
+
+// An autoTuner implements Config.AutoTune. It adjusts the number of active
+// workers of a scheduler using hill climbing: starting with
+// autoTuneMinWorkers, the number of workers is increased while doing so
+// increases the rate at which directories are read. Once the throughput
+// plateaus the tuner settles on the smallest number of workers that reached
+// the best throughput and only removes workers if the time to read a
+// directory degrades, which happens when the storage becomes congested.
+type autoTuner struct {
+	s    *scheduler
+	min  int
+	max  int
+	done chan struct{}
+
+	dirs    atomic.Int64 // directories read
+	elapsed atomic.Int64 // total time spent reading directories (ns)
+
+	// state of step
+	active      int
+	plateau     bool
+	bestRate    float64       // directories per second
+	bestActive  int           // number of workers at bestRate
+	bestLatency time.Duration // mean time to read a directory at bestRate
+	settled     int           // number of workers chosen once throughput plateaued
+}
+
+func newAutoTuner(s *scheduler) *autoTuner {
+	t := &autoTuner{
+		s:    s,
+		min:  autoTuneMinWorkers,
+		max:  len(s.queues),
+		done: make(chan struct{}),
+	}
+	if t.min > t.max {
+		t.min = t.max
+	}
+	t.active = t.min
+	t.bestActive = t.min
+	s.setActive(t.active)
+	return t
+}
+
+// record records that reading a directory took d.
+func (t *autoTuner) record(d time.Duration) {
+	t.dirs.Add(1)
+	t.elapsed.Add(int64(d))
+}
+
+// run periodically adjusts the number of active workers until stop is
+// called.
+func (t *autoTuner) run() {
+	tick := time.NewTicker(autoTuneInterval)
+	defer tick.Stop()
+	start := time.Now()
+	var lastDirs, lastElapsed int64
+	for {
+		select {
+		case <-t.done:
+			return
+		case now := <-tick.C:
+			dirs := t.dirs.Load()
+			elapsed := t.elapsed.Load()
+			n := dirs - lastDirs
+			if n < autoTuneMinDirs {
+				continue // Not enough data
+			}
+			rate := float64(n) / now.Sub(start).Seconds()
+			latency := time.Duration((elapsed - lastElapsed) / n)
+			// Only grow if there is enough work to keep the active
+			// workers busy, otherwise the number of workers is not
+			// what limits throughput.
+			busy := t.s.idle.Load() == 0
+			if active := t.step(rate, latency, busy); active != t.active {
+				t.active = active
+				t.s.setActive(active)
+			}
+			start = now
+			lastDirs = dirs
+			lastElapsed = elapsed
+		}
+	}
+}
+
+// stop stops the tuner.
+func (t *autoTuner) stop() {
+	close(t.done)
+}
+
+// step returns the number of workers to use given the throughput (rate) and
+// mean time to read a directory (latency) that were measured with the
+// current number of workers.
+func (t *autoTuner) step(rate float64, latency time.Duration, busy bool) int {
+	n := t.active
+	if rate > t.bestRate*(1+autoTuneGain) {
+		t.bestRate = rate
+		t.bestActive = n
+		t.bestLatency = latency
+		if !t.plateau && busy && n < t.max {
+			// Grow by half, which is less likely than doubling
+			// to overshoot the optimal number of workers.
+			n += (n + 1) / 2
+			if n > t.max {
+				n = t.max
+			}
+		}
+		return n
+	}
+	if !t.plateau {
+		if !busy {
+			return n // Not limited by the number of workers: measure again.
+		}
+		// Throughput plateaued: stop scaling up and use the smallest
+		// number of workers that reached the best throughput.
+		t.plateau = true
+		t.settled = t.bestActive
+		return t.bestActive
+	}
+	switch {
+	case latency > t.bestLatency*autoTuneSlowdown && n > t.min:
+		// The storage slowed down: back off.
+		n--
+	case latency*autoTuneSlowdown < t.bestLatency && n < t.settled:
+		// The storage recovered after backing off.
+		n++
+	default:
+		return n
+	}
+	// Use this measurement as the new baseline.
+	t.bestRate = rate
+	t.bestActive = n
+	t.bestLatency = latency
+	return n
+}
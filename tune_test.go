@@ -0,0 +1,78 @@
+package fastwalk
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutoTunerStep(t *testing.T) {
+	tuner := newAutoTuner(newScheduler(16, DepthFirst))
+	type sample struct {
+		rate    float64
+		latency time.Duration
+		busy    bool
+		want    int
+	}
+	for i, x := range []sample{
+		{100, time.Millisecond, true, 3},
+		{200, time.Millisecond, true, 5},
+		{300, time.Millisecond, false, 5}, // improved but not busy: don't grow
+		{400, time.Millisecond, true, 8},
+		{405, time.Millisecond, false, 8}, // not busy: not a plateau
+		{405, time.Millisecond, true, 5},  // plateau: use the best count
+		{500, time.Millisecond, true, 5},  // no longer grows
+		{500, 3 * time.Millisecond, true, 4},
+		{500, 7 * time.Millisecond, true, 3},
+		{500, 7 * time.Millisecond, true, 3},
+		{500, time.Millisecond, true, 4}, // recovered
+		{500, time.Millisecond / 4, true, 5},
+		{500, time.Millisecond / 16, true, 5}, // never exceeds the settled count
+	} {
+		got := tuner.step(x.rate, x.latency, x.busy)
+		if got != x.want {
+			t.Fatalf("%d: step(%v, %v, %t) = %d; want: %d", i, x.rate, x.latency, x.busy, got, x.want)
+		}
+		tuner.active = got
+	}
+}
+
+func TestAutoTunerMax(t *testing.T) {
+	tuner := newAutoTuner(newScheduler(1, DepthFirst))
+	if tuner.active != 1 {
+		t.Errorf("active = %d; want: 1", tuner.active)
+	}
+	if n := tuner.step(100, time.Millisecond, true); n != 1 {
+		t.Errorf("step = %d; want: 1", n)
+	}
+}
+
+func TestSchedulerPark(t *testing.T) {
+	s := newScheduler(2, DepthFirst)
+	s.setActive(1)
+	s.push(1, walkItem{dir: "a"})
+
+	parked := make(chan walkItem)
+	go func() {
+		it, _ := s.next(1)
+		parked <- it
+	}()
+	// Work in the queue of a parked worker is stolen by active workers.
+	if it, ok := s.next(0); !ok || it.dir != "a" {
+		t.Fatalf("next(0) = %q, %t; want: %q, true", it.dir, ok, "a")
+	}
+	select {
+	case it := <-parked:
+		t.Fatalf("parked worker returned: %q", it.dir)
+	case <-time.After(10 * time.Millisecond):
+	}
+	s.push(0, walkItem{dir: "b"})
+	s.setActive(2)
+	select {
+	case it := <-parked:
+		if it.dir != "b" {
+			t.Errorf("next(1) = %q; want: %q", it.dir, "b")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker was not unparked")
+	}
+}
@@ -0,0 +1,21 @@
+//go:build linux
+
+package fastwalk
+
+import "syscall"
+
+// hasXattr returns if the file at path has extended attribute attr.
+func hasXattr(path, attr string) bool {
+	var buf [64]byte
+	for {
+		_, err := syscall.Getxattr(path, attr, buf[:])
+		switch err {
+		case nil, syscall.ERANGE:
+			// ERANGE means the value is larger than buf, but it exists.
+			return true
+		case syscall.EINTR:
+			continue
+		}
+		return false
+	}
+}
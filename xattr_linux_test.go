@@ -0,0 +1,54 @@
+//go:build linux
+
+package fastwalk_test
+
+import (
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/charlievieth/fastwalk"
+)
+
+func TestIgnoreHiddenXattr(t *testing.T) {
+	const attr = "user.hidden"
+	tempdir := t.TempDir()
+	for _, name := range []string{"visible.txt", "hidden.txt", "hidden/file.txt"} {
+		if err := writeFile(filepath.Join(tempdir, name), name, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"hidden.txt", "hidden"} {
+		err := syscall.Setxattr(filepath.Join(tempdir, name), attr, []byte("1"), 0)
+		if err != nil {
+			t.Skipf("extended attributes appear to be unsupported: %v", err)
+		}
+	}
+
+	var mu sync.Mutex
+	var got []string
+	walkFn := fastwalk.IgnoreHiddenWith(fastwalk.IgnoreHiddenOptions{Xattr: attr})(
+		func(path string, _ fs.DirEntry, err error) error {
+			requireNoError(t, err)
+			rel, err := filepath.Rel(tempdir, path)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			got = append(got, rel)
+			mu.Unlock()
+			return nil
+		})
+	if err := fastwalk.Walk(nil, tempdir, walkFn); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	want := []string{".", "visible.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %q want: %q", got, want)
+	}
+}
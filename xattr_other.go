@@ -0,0 +1,7 @@
+//go:build !linux
+
+package fastwalk
+
+func hasXattr(path, attr string) bool {
+	return false
+}